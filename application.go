@@ -0,0 +1,56 @@
+// SPDX-License-Identifier: MIT
+
+package webview
+
+// Application 应用程序
+//
+// 拥有主线程上的消息循环，负责创建和管理所有的窗口，由各平台包的 NewApplication 创建。
+// 与 [Desktop] 不同，关闭窗口并不会退出程序，只有调用 Quit 之后 Run 才会返回：
+//
+//	app := gtk.NewApplication()
+//	app.OnReady(func() {
+//	    settings, _ := app.NewWindow(&gtk.Options{Title: "settings"})
+//	    inspector, _ := app.NewWindow(&gtk.Options{Title: "inspector"})
+//	    ...
+//	})
+//	app.Run()
+//
+// 除了 Quit 之外，其它方法都应该在主线程中调用。
+type Application interface {
+	// NewWindow 创建新的窗口
+	//
+	// o 为各平台包中的 *Options，为 nil 表示采用默认值，其它类型返回 [ErrInvalidOptions]。
+	// 可以在 Run 之前或是之后调用，窗口创建之后即显示。
+	NewWindow(o interface{}) (Desktop, error)
+
+	// Windows 所有未关闭的窗口
+	//
+	// 按创建的顺序排列。
+	Windows() []Desktop
+
+	// OnReady 注册进入消息循环之前执行的函数
+	//
+	// 通常在此创建窗口，多次注册会按顺序执行。
+	OnReady(f func())
+
+	// OnAllWindowsClosed 注册所有窗口都关闭之后执行的函数
+	//
+	// 如果没有注册任何函数，最后一个窗口关闭之后会自动调用 Quit；
+	// 否则由注册的函数决定是否调用 Quit，比如仅保留托盘图标的程序。
+	OnAllWindowsClosed(f func())
+
+	// OnQuit 注册消息循环退出之后执行的函数
+	//
+	// 在 Run 返回之前执行，此时所有的窗口都已经关闭。
+	OnQuit(f func())
+
+	// Run 运行消息循环
+	//
+	// 直到调用 Quit 之后才返回，在消息循环中再次调用会直接返回。
+	Run()
+
+	// Quit 关闭所有窗口并退出消息循环
+	//
+	// 可以在任意 goroutine 中调用，通过 OnAllWindowsClosed 注册的函数不会被执行。
+	Quit()
+}
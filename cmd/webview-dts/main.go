@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: MIT
+
+// webview-dts 为绑定的方法生成 TypeScript 声明文件
+//
+// 指定的包中需要有一个类型为 func(webview.App) error 的导出函数，在其中完成所有的绑定操作，
+// webview-dts 会生成并运行一个调用该函数的临时程序，最终输出所有绑定方法的声明：
+//
+//	webview-dts -pkg ./api -func Register -o ./web/bindings.d.ts
+//
+// 临时程序创建于当前目录之下，所以需要在指定包所在的模块中执行。
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const program = `package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/issue9/webview/webviewtest"
+
+	pkg %q
+)
+
+func main() {
+	app := webviewtest.NewApp()
+	if err := pkg.%s(app); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := app.TypeScript(os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+`
+
+func main() {
+	pkg := flag.String("pkg", ".", "包含绑定函数的包，可以是导入路径或是相对路径")
+	fn := flag.String("func", "Register", "完成绑定操作的函数名，类型必须为 func(webview.App) error")
+	out := flag.String("o", "", "输出的文件，为空表示输出到标准输出")
+	flag.Parse()
+
+	if err := run(*pkg, *fn, *out); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(pkg, fn, out string) error {
+	path, err := importPath(pkg)
+	if err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp(".", "webview_dts_")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(fmt.Sprintf(program, path, fn)), 0o644); err != nil {
+		return err
+	}
+
+	stdout := &bytes.Buffer{}
+	cmd := exec.Command("go", "run", "./"+filepath.ToSlash(dir))
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return err
+	}
+
+	if out == "" {
+		_, err = stdout.WriteTo(os.Stdout)
+		return err
+	}
+	return os.WriteFile(out, stdout.Bytes(), 0o644)
+}
+
+// 将 pkg 转换为导入路径
+func importPath(pkg string) (string, error) {
+	stdout := &bytes.Buffer{}
+	cmd := exec.Command("go", "list", "-f", "{{.ImportPath}}", pkg)
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,27 @@
+// SPDX-License-Identifier: MIT
+
+// Package codec 提供了几种常用的 webview.Codec 实现
+//
+// 各实现对 Go 类型的处理方式尽量与 encoding/json 保持一致，比如结构体字段采用 json 标签，
+// 实现了 json.Marshaler 等接口的类型也能正常编码，这样在切换编码方式时不需要修改绑定的方法。
+package codec
+
+import "reflect"
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,21 @@
+// SPDX-License-Identifier: MIT
+
+package codec
+
+type Base struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type embedded struct {
+	Embedded string
+}
+
+type object struct {
+	*Base
+	embedded
+	Name     string `json:"name,omitempty"`
+	Ignored  int    `json:"-"`
+	internal int
+	Tags     []string
+}
@@ -0,0 +1,625 @@
+// SPDX-License-Identifier: MIT
+
+package codec
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/issue9/webview/internal/structs"
+)
+
+// 数组和 map 最大的嵌套层数，与 encoding/json 相同。
+const maxDepth = 10000
+
+var (
+	errShortData        = errors.New("msgpack: unexpected end of data")
+	errMaxDepth         = errors.New("msgpack: exceeded max depth")
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+// MessagePack 中的类型
+const (
+	typeNil = iota
+	typeBool
+	typeInt
+	typeUint
+	typeFloat
+	typeStr
+	typeBin
+	typeArray
+	typeMap
+	typeExt
+)
+
+var typeNames = []string{"nil", "bool", "int", "uint", "float", "str", "bin", "array", "map", "ext"}
+
+// 数据的头部信息
+type token struct {
+	typ int
+	b   bool
+	i   int64
+	u   uint64
+	f   float64
+	n   int // str、bin、array、map 和 ext 的长度
+}
+
+// MessagePack 的解码器
+type decoder struct {
+	data   []byte
+	off    int
+	strict bool // 不允许未知的字段，通用类型中的数值解码为 json.Number。
+	depth  int  // 当前数组和 map 的嵌套层数
+}
+
+func unmarshal(data []byte, v interface{}, strict bool) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("msgpack: unmarshal to non-pointer %T", v)
+	}
+
+	d := &decoder{data: data, strict: strict}
+	if err := d.decode(rv.Elem()); err != nil {
+		return err
+	}
+	if d.off != len(d.data) {
+		return errors.New("msgpack: invalid data after top-level value")
+	}
+	return nil
+}
+
+func (d *decoder) decode(v reflect.Value) error {
+	// 与 encoding/json 相同，nil 会将指针等类型置空，但 json.RawMessage 等类型依然交由其自身处理。
+	if d.off < len(d.data) && d.data[d.off] == 0xc0 && !(v.CanAddr() && reflect.PtrTo(v.Type()).Implements(jsonUnmarshalerType)) {
+		switch v.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice:
+			d.off++
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+	}
+
+	ju, tu, v := indirect(v)
+	if ju != nil {
+		x, err := d.any()
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(x)
+		if err != nil {
+			return err
+		}
+		return ju.UnmarshalJSON(data)
+	}
+
+	t, err := d.next()
+	if err != nil {
+		return err
+	}
+	if tu != nil && (t.typ == typeStr || t.typ == typeBin) {
+		data, err := d.bytes(t.n)
+		if err != nil {
+			return err
+		}
+		return tu.UnmarshalText(data)
+	}
+
+	if v.Kind() == reflect.Interface && v.NumMethod() == 0 {
+		x, err := d.value(t)
+		if err != nil {
+			return err
+		}
+		if x != nil {
+			v.Set(reflect.ValueOf(x))
+		} else {
+			v.Set(reflect.Zero(v.Type()))
+		}
+		return nil
+	}
+
+	switch t.typ {
+	case typeNil: // 与 encoding/json 相同，不改变原有的值。
+		return nil
+	case typeBool:
+		if v.Kind() != reflect.Bool {
+			return typeError(t, v)
+		}
+		v.SetBool(t.b)
+	case typeInt, typeUint, typeFloat:
+		return setNumber(t, v)
+	case typeStr, typeBin:
+		data, err := d.bytes(t.n)
+		if err != nil {
+			return err
+		}
+		switch {
+		case v.Kind() == reflect.String:
+			v.SetString(string(data))
+		case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+			v.SetBytes(append([]byte{}, data...))
+		default:
+			return typeError(t, v)
+		}
+	case typeArray:
+		if err := d.enter(t); err != nil {
+			return err
+		}
+		defer d.leave(t)
+		return d.array(t, v)
+	case typeMap:
+		if err := d.enter(t); err != nil {
+			return err
+		}
+		defer d.leave(t)
+		switch v.Kind() {
+		case reflect.Map:
+			return d.mapValue(t, v)
+		case reflect.Struct:
+			return d.structValue(t, v)
+		default:
+			return typeError(t, v)
+		}
+	default:
+		return typeError(t, v)
+	}
+	return nil
+}
+
+// 分配 v 中的指针直到非指针类型，如果遇到实现了 json.Unmarshaler 或是 encoding.TextUnmarshaler 的类型则返回。
+func indirect(v reflect.Value) (json.Unmarshaler, encoding.TextUnmarshaler, reflect.Value) {
+	for {
+		if v.Kind() != reflect.Ptr && v.CanAddr() && v.Addr().CanInterface() {
+			switch u := v.Addr().Interface().(type) {
+			case json.Unmarshaler:
+				return u, nil, v
+			case encoding.TextUnmarshaler:
+				return nil, u, v
+			}
+		}
+
+		if v.Kind() != reflect.Ptr {
+			return nil, nil, v
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+}
+
+func (d *decoder) array(t token, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Slice:
+		s := reflect.MakeSlice(v.Type(), t.n, t.n)
+		for i := 0; i < t.n; i++ {
+			if err := d.decode(s.Index(i)); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+	case reflect.Array:
+		for i := 0; i < t.n; i++ {
+			if i >= v.Len() {
+				if err := d.skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := d.decode(v.Index(i)); err != nil {
+				return err
+			}
+		}
+		for i := t.n; i < v.Len(); i++ {
+			v.Index(i).Set(reflect.Zero(v.Type().Elem()))
+		}
+	default:
+		return typeError(t, v)
+	}
+	return nil
+}
+
+func (d *decoder) mapValue(t token, v reflect.Value) error {
+	if v.IsNil() {
+		v.Set(reflect.MakeMapWithSize(v.Type(), t.n))
+	}
+
+	kt := v.Type().Key()
+	for i := 0; i < t.n; i++ {
+		key, err := d.key()
+		if err != nil {
+			return err
+		}
+
+		kv := reflect.New(kt).Elem()
+		switch {
+		case kt.Kind() == reflect.String:
+			kv.SetString(key)
+		case reflect.PtrTo(kt).Implements(textUnmarshalerType):
+			if err := kv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(key)); err != nil {
+				return err
+			}
+		default:
+			if err := setKey(key, kv); err != nil {
+				return err
+			}
+		}
+
+		ev := reflect.New(v.Type().Elem()).Elem()
+		if err := d.decode(ev); err != nil {
+			return err
+		}
+		v.SetMapIndex(kv, ev)
+	}
+	return nil
+}
+
+// 将字符串形式的键名写入整数类型的 v
+func setKey(key string, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(key, 10, 64)
+		if err != nil {
+			return err
+		}
+		return setNumber(token{typ: typeInt, i: i}, v)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, err := strconv.ParseUint(key, 10, 64)
+		if err != nil {
+			return err
+		}
+		return setNumber(token{typ: typeUint, u: u}, v)
+	default:
+		return fmt.Errorf("msgpack: unsupported map key type %s", v.Type())
+	}
+}
+
+// 字段名的匹配规则与 encoding/json 相同，优先完全匹配，其次不区分大小写，不存在的字段会被忽略。
+func (d *decoder) structValue(t token, v reflect.Value) error {
+	fields := structs.Fields(v.Type())
+	for i := 0; i < t.n; i++ {
+		key, err := d.key()
+		if err != nil {
+			return err
+		}
+
+		var f *structs.Field
+		for _, item := range fields {
+			if item.Name == key {
+				f = item
+				break
+			}
+			if f == nil && strings.EqualFold(item.Name, key) {
+				f = item
+			}
+		}
+		if f == nil {
+			if d.strict {
+				return fmt.Errorf("msgpack: unknown field %q", key)
+			}
+			if err := d.skip(); err != nil {
+				return err
+			}
+			continue
+		}
+
+		fv := v
+		for j, x := range f.Index {
+			if j > 0 && fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					fv.Set(reflect.New(fv.Type().Elem()))
+				}
+				fv = fv.Elem()
+			}
+			fv = fv.Field(x)
+		}
+		if err := d.decode(fv); err != nil {
+			return fmt.Errorf("msgpack: field %s: %w", f.Name, err)
+		}
+	}
+	return nil
+}
+
+// 读取 map 的键名
+//
+// 键名可以是字符串或是整数，整数会被转换为字符串。
+func (d *decoder) key() (string, error) {
+	t, err := d.next()
+	if err != nil {
+		return "", err
+	}
+
+	switch t.typ {
+	case typeStr, typeBin:
+		data, err := d.bytes(t.n)
+		return string(data), err
+	case typeInt:
+		return strconv.FormatInt(t.i, 10), nil
+	case typeUint:
+		return strconv.FormatUint(t.u, 10), nil
+	default:
+		return "", fmt.Errorf("msgpack: invalid map key type %s", typeNames[t.typ])
+	}
+}
+
+// 读取一个值并转换为 Go 中的通用类型
+//
+// 与 encoding/json 相同，数值都转换为 float64，数组和 map 分别转换为 []interface{} 和 map[string]interface{}，
+// bin 则为 []byte。
+func (d *decoder) any() (interface{}, error) {
+	t, err := d.next()
+	if err != nil {
+		return nil, err
+	}
+	return d.value(t)
+}
+
+func (d *decoder) value(t token) (interface{}, error) {
+	if err := d.enter(t); err != nil {
+		return nil, err
+	}
+	defer d.leave(t)
+
+	switch t.typ {
+	case typeBool:
+		return t.b, nil
+	case typeInt:
+		if d.strict {
+			return json.Number(strconv.FormatInt(t.i, 10)), nil
+		}
+		return float64(t.i), nil
+	case typeUint:
+		if d.strict {
+			return json.Number(strconv.FormatUint(t.u, 10)), nil
+		}
+		return float64(t.u), nil
+	case typeFloat:
+		if d.strict {
+			return json.Number(strconv.FormatFloat(t.f, 'g', -1, 64)), nil
+		}
+		return t.f, nil
+	case typeStr:
+		data, err := d.bytes(t.n)
+		return string(data), err
+	case typeBin:
+		data, err := d.bytes(t.n)
+		return append([]byte{}, data...), err
+	case typeArray:
+		arr := make([]interface{}, 0, t.n)
+		for i := 0; i < t.n; i++ {
+			item, err := d.any()
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, item)
+		}
+		return arr, nil
+	case typeMap:
+		m := make(map[string]interface{}, t.n)
+		for i := 0; i < t.n; i++ {
+			key, err := d.key()
+			if err != nil {
+				return nil, err
+			}
+			if m[key], err = d.any(); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case typeExt:
+		_, err := d.bytes(t.n)
+		return nil, err
+	default: // typeNil
+		return nil, nil
+	}
+}
+
+// 进入数组或 map，超出 maxDepth 时返回错误，防止恶意的数据导致栈溢出。
+func (d *decoder) enter(t token) error {
+	if t.typ == typeArray || t.typ == typeMap {
+		if d.depth++; d.depth > maxDepth {
+			return errMaxDepth
+		}
+	}
+	return nil
+}
+
+func (d *decoder) leave(t token) {
+	if t.typ == typeArray || t.typ == typeMap {
+		d.depth--
+	}
+}
+
+// 跳过一个值
+func (d *decoder) skip() error {
+	_, err := d.any()
+	return err
+}
+
+// 读取下一个值的头部信息
+//
+// 对于 str、bin 和 ext，只读取了长度，内容需要通过 bytes 读取；
+// 对于 array 和 map，则需要继续读取其元素。
+func (d *decoder) next() (token, error) {
+	if d.off >= len(d.data) {
+		return token{}, errShortData
+	}
+	c := d.data[d.off]
+	d.off++
+
+	switch {
+	case c <= 0x7f:
+		return token{typ: typeUint, u: uint64(c)}, nil
+	case c <= 0x8f:
+		return token{typ: typeMap, n: int(c & 0x0f)}, nil
+	case c <= 0x9f:
+		return token{typ: typeArray, n: int(c & 0x0f)}, nil
+	case c <= 0xbf:
+		return token{typ: typeStr, n: int(c & 0x1f)}, nil
+	case c >= 0xe0:
+		return token{typ: typeInt, i: int64(int8(c))}, nil
+	}
+
+	switch c {
+	case 0xc0:
+		return token{typ: typeNil}, nil
+	case 0xc2, 0xc3:
+		return token{typ: typeBool, b: c == 0xc3}, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.uint(1 << (c - 0xc4))
+		return token{typ: typeBin, n: int(n)}, err
+	case 0xc7, 0xc8, 0xc9:
+		n, err := d.uint(1 << (c - 0xc7))
+		if err == nil {
+			_, err = d.uint(1) // 扩展类型的类型值
+		}
+		return token{typ: typeExt, n: int(n)}, err
+	case 0xca:
+		u, err := d.uint(4)
+		return token{typ: typeFloat, f: float64(math.Float32frombits(uint32(u)))}, err
+	case 0xcb:
+		u, err := d.uint(8)
+		return token{typ: typeFloat, f: math.Float64frombits(u)}, err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		u, err := d.uint(1 << (c - 0xcc))
+		return token{typ: typeUint, u: u}, err
+	case 0xd0:
+		u, err := d.uint(1)
+		return token{typ: typeInt, i: int64(int8(u))}, err
+	case 0xd1:
+		u, err := d.uint(2)
+		return token{typ: typeInt, i: int64(int16(u))}, err
+	case 0xd2:
+		u, err := d.uint(4)
+		return token{typ: typeInt, i: int64(int32(u))}, err
+	case 0xd3:
+		u, err := d.uint(8)
+		return token{typ: typeInt, i: int64(u)}, err
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		_, err := d.uint(1) // 扩展类型的类型值
+		return token{typ: typeExt, n: 1 << (c - 0xd4)}, err
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.uint(1 << (c - 0xd9))
+		return token{typ: typeStr, n: int(n)}, err
+	case 0xdc, 0xdd:
+		n, err := d.uint(2 << (c - 0xdc))
+		if err == nil && n > uint64(len(d.data)-d.off) { // 每个元素至少占一个字节
+			err = errShortData
+		}
+		return token{typ: typeArray, n: int(n)}, err
+	case 0xde, 0xdf:
+		n, err := d.uint(2 << (c - 0xde))
+		if err == nil && n*2 > uint64(len(d.data)-d.off) {
+			err = errShortData
+		}
+		return token{typ: typeMap, n: int(n)}, err
+	}
+
+	return token{}, fmt.Errorf("msgpack: invalid type 0x%x", c)
+}
+
+// 以大端序读取 n 个字节的无符号整数
+func (d *decoder) uint(n int) (uint64, error) {
+	data, err := d.bytes(n)
+	if err != nil {
+		return 0, err
+	}
+
+	var u uint64
+	for _, b := range data {
+		u = u<<8 | uint64(b)
+	}
+	return u, nil
+}
+
+func (d *decoder) bytes(n int) ([]byte, error) {
+	if n < 0 || n > len(d.data)-d.off {
+		return nil, errShortData
+	}
+	data := d.data[d.off : d.off+n]
+	d.off += n
+	return data, nil
+}
+
+// 将数值类型的 t 写入 v
+func setNumber(t token, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		switch t.typ {
+		case typeInt:
+			i = t.i
+		case typeUint:
+			if t.u > math.MaxInt64 {
+				return overflowError(t, v)
+			}
+			i = int64(t.u)
+		case typeFloat:
+			if t.f != math.Trunc(t.f) || t.f < math.MinInt64 || t.f >= math.MaxInt64 {
+				return typeError(t, v)
+			}
+			i = int64(t.f)
+		default:
+			return typeError(t, v)
+		}
+		if v.OverflowInt(i) {
+			return overflowError(t, v)
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var u uint64
+		switch t.typ {
+		case typeInt:
+			if t.i < 0 {
+				return overflowError(t, v)
+			}
+			u = uint64(t.i)
+		case typeUint:
+			u = t.u
+		case typeFloat:
+			if t.f != math.Trunc(t.f) || t.f < 0 || t.f >= math.MaxUint64 {
+				return typeError(t, v)
+			}
+			u = uint64(t.f)
+		default:
+			return typeError(t, v)
+		}
+		if v.OverflowUint(u) {
+			return overflowError(t, v)
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		switch t.typ {
+		case typeInt:
+			f = float64(t.i)
+		case typeUint:
+			f = float64(t.u)
+		case typeFloat:
+			f = t.f
+		default:
+			return typeError(t, v)
+		}
+		if v.OverflowFloat(f) {
+			return overflowError(t, v)
+		}
+		v.SetFloat(f)
+	default:
+		return typeError(t, v)
+	}
+	return nil
+}
+
+func typeError(t token, v reflect.Value) error {
+	return fmt.Errorf("msgpack: cannot unmarshal %s into Go value of type %s", typeNames[t.typ], v.Type())
+}
+
+func overflowError(t token, v reflect.Value) error {
+	return fmt.Errorf("msgpack: %s overflows Go value of type %s", typeNames[t.typ], v.Type())
+}
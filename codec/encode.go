@@ -0,0 +1,361 @@
+// SPDX-License-Identifier: MIT
+
+package codec
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/issue9/webview/internal/structs"
+)
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// 指针的嵌套层数超过此值之后才开始检测循环引用，与 encoding/json 相同。
+const startDetectingCyclesAfter = 1000
+
+// MessagePack 的编码器
+type encoder struct {
+	buf      []byte
+	ptrLevel uint
+	ptrSeen  map[pointer]struct{}
+}
+
+// 指针、map 和 slice 的标识
+type pointer struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+func (e *encoder) encode(v reflect.Value) error {
+	if !v.IsValid() {
+		e.nil()
+		return nil
+	}
+
+	if !v.CanInterface() { // 非导出的嵌入结构体中的字段，无法调用其方法。
+		return e.kind(v)
+	}
+
+	if v.Kind() != reflect.Ptr && v.CanAddr() && reflect.PtrTo(v.Type()).Implements(jsonMarshalerType) {
+		v = v.Addr()
+	}
+	if v.Type().Implements(jsonMarshalerType) {
+		if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+			e.nil()
+			return nil
+		}
+		return e.marshaler(v.Interface().(json.Marshaler))
+	}
+
+	if v.Kind() != reflect.Ptr && v.CanAddr() && reflect.PtrTo(v.Type()).Implements(textMarshalerType) {
+		v = v.Addr()
+	}
+	if v.Type().Implements(textMarshalerType) {
+		if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+			e.nil()
+			return nil
+		}
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return err
+		}
+		e.str(string(text))
+		return nil
+	}
+
+	return e.kind(v)
+}
+
+// 根据 v 的类型编码
+func (e *encoder) kind(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			e.buf = append(e.buf, 0xc3)
+		} else {
+			e.buf = append(e.buf, 0xc2)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.int(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.uint(v.Uint())
+	case reflect.Float32:
+		e.buf = append(e.buf, 0xca)
+		e.buf = appendUint(e.buf, uint64(math.Float32bits(float32(v.Float()))), 4)
+	case reflect.Float64:
+		e.float(v.Float())
+	case reflect.String:
+		e.str(v.String())
+	case reflect.Interface:
+		if v.IsNil() {
+			e.nil()
+			return nil
+		}
+		return e.encode(v.Elem())
+	case reflect.Ptr:
+		if v.IsNil() {
+			e.nil()
+			return nil
+		}
+		leave, err := e.enter(v)
+		if err != nil {
+			return err
+		}
+		defer leave()
+		return e.encode(v.Elem())
+	case reflect.Slice:
+		if v.IsNil() {
+			e.nil()
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			e.bin(v.Bytes())
+			return nil
+		}
+		leave, err := e.enter(v)
+		if err != nil {
+			return err
+		}
+		defer leave()
+		return e.array(v)
+	case reflect.Array:
+		return e.array(v)
+	case reflect.Map:
+		if v.IsNil() {
+			e.nil()
+			return nil
+		}
+		leave, err := e.enter(v)
+		if err != nil {
+			return err
+		}
+		defer leave()
+		return e.mapValue(v)
+	case reflect.Struct:
+		return e.structValue(v)
+	default:
+		return fmt.Errorf("msgpack: unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// 进入指针、map 或 slice v
+//
+// 与 encoding/json 相同，嵌套层数较多时才开始检测循环引用，存在循环引用时返回 *json.UnsupportedValueError。
+// 返回的函数用于离开 v。
+func (e *encoder) enter(v reflect.Value) (func(), error) {
+	if e.ptrLevel++; e.ptrLevel <= startDetectingCyclesAfter {
+		return func() { e.ptrLevel-- }, nil
+	}
+
+	p := pointer{ptr: v.Pointer(), typ: v.Type()}
+	if v.Kind() == reflect.Slice {
+		p.len = v.Len()
+	}
+	if _, found := e.ptrSeen[p]; found {
+		e.ptrLevel--
+		return nil, &json.UnsupportedValueError{Value: v, Str: fmt.Sprintf("encountered a cycle via %s", v.Type())}
+	}
+	if e.ptrSeen == nil {
+		e.ptrSeen = make(map[pointer]struct{})
+	}
+	e.ptrSeen[p] = struct{}{}
+
+	return func() {
+		delete(e.ptrSeen, p)
+		e.ptrLevel--
+	}, nil
+}
+
+// 实现了 json.Marshaler 的对象，先经由 JSON 转换为通用的类型再编码。
+func (e *encoder) marshaler(m json.Marshaler) error {
+	data, err := m.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	return e.encode(reflect.ValueOf(v))
+}
+
+func (e *encoder) array(v reflect.Value) error {
+	e.head(v.Len(), 0x90, 16, 0, 0xdc, 0xdd)
+	for i := 0; i < v.Len(); i++ {
+		if err := e.encode(v.Index(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 与 encoding/json 相同，键名都会被转换为字符串，并按字符串排序。
+func (e *encoder) mapValue(v reflect.Value) error {
+	type kv struct {
+		key string
+		val reflect.Value
+	}
+
+	kvs := make([]kv, 0, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		key, err := mapKey(iter.Key())
+		if err != nil {
+			return err
+		}
+		kvs = append(kvs, kv{key: key, val: iter.Value()})
+	}
+	sort.Slice(kvs, func(i, j int) bool { return kvs[i].key < kvs[j].key })
+
+	e.head(len(kvs), 0x80, 16, 0, 0xde, 0xdf)
+	for _, item := range kvs {
+		e.str(item.key)
+		if err := e.encode(item.val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func mapKey(k reflect.Value) (string, error) {
+	if k.Kind() == reflect.String {
+		return k.String(), nil
+	}
+	if tm, ok := k.Interface().(encoding.TextMarshaler); ok {
+		if k.Kind() == reflect.Ptr && k.IsNil() {
+			return "", nil
+		}
+		text, err := tm.MarshalText()
+		return string(text), err
+	}
+
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10), nil
+	}
+	return "", fmt.Errorf("msgpack: unsupported map key type %s", k.Type())
+}
+
+func (e *encoder) structValue(v reflect.Value) error {
+	fields := structs.Fields(v.Type())
+	vals := make([]reflect.Value, 0, len(fields))
+	names := make([]string, 0, len(fields))
+	for _, f := range fields {
+		fv, ok := fieldByIndex(v, f.Index)
+		if !ok || (f.OmitEmpty && isEmptyValue(fv)) {
+			continue
+		}
+		vals = append(vals, fv)
+		names = append(names, f.Name)
+	}
+
+	e.head(len(vals), 0x80, 16, 0, 0xde, 0xdf)
+	for i, fv := range vals {
+		e.str(names[i])
+		if err := e.encode(fv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 获取字段的值，如果中间嵌入的指针为 nil，则返回 false。
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+func (e *encoder) nil() { e.buf = append(e.buf, 0xc0) }
+
+func (e *encoder) int(i int64) {
+	switch {
+	case i >= 0:
+		e.uint(uint64(i))
+	case i >= -32:
+		e.buf = append(e.buf, byte(i))
+	case i >= math.MinInt8:
+		e.buf = append(e.buf, 0xd0, byte(i))
+	case i >= math.MinInt16:
+		e.buf = appendUint(append(e.buf, 0xd1), uint64(i), 2)
+	case i >= math.MinInt32:
+		e.buf = appendUint(append(e.buf, 0xd2), uint64(i), 4)
+	default:
+		e.buf = appendUint(append(e.buf, 0xd3), uint64(i), 8)
+	}
+}
+
+func (e *encoder) uint(u uint64) {
+	switch {
+	case u <= 0x7f:
+		e.buf = append(e.buf, byte(u))
+	case u <= math.MaxUint8:
+		e.buf = append(e.buf, 0xcc, byte(u))
+	case u <= math.MaxUint16:
+		e.buf = appendUint(append(e.buf, 0xcd), u, 2)
+	case u <= math.MaxUint32:
+		e.buf = appendUint(append(e.buf, 0xce), u, 4)
+	default:
+		e.buf = appendUint(append(e.buf, 0xcf), u, 8)
+	}
+}
+
+func (e *encoder) float(f float64) {
+	e.buf = appendUint(append(e.buf, 0xcb), math.Float64bits(f), 8)
+}
+
+func (e *encoder) str(s string) {
+	e.head(len(s), 0xa0, 32, 0xd9, 0xda, 0xdb)
+	e.buf = append(e.buf, s...)
+}
+
+func (e *encoder) bin(b []byte) {
+	e.head(len(b), 0, 0, 0xc4, 0xc5, 0xc6)
+	e.buf = append(e.buf, b...)
+}
+
+// 写入长度信息
+//
+// 长度小于 fixMax 时采用 fix 格式，c8、c16 和 c32 分别为长度采用 8、16 和 32 位表示的格式，
+// 值为 0 表示不支持该格式。
+func (e *encoder) head(n int, fix byte, fixMax int, c8, c16, c32 byte) {
+	switch {
+	case n < fixMax:
+		e.buf = append(e.buf, fix|byte(n))
+	case c8 != 0 && n <= math.MaxUint8:
+		e.buf = append(e.buf, c8, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = appendUint(append(e.buf, c16), uint64(n), 2)
+	default:
+		e.buf = appendUint(append(e.buf, c32), uint64(n), 4)
+	}
+}
+
+// 以大端序写入 u 的低 n 个字节
+func appendUint(buf []byte, u uint64, n int) []byte {
+	for i := n - 1; i >= 0; i-- {
+		buf = append(buf, byte(u>>(8*i)))
+	}
+	return buf
+}
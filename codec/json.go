@@ -0,0 +1,54 @@
+// SPDX-License-Identifier: MIT
+
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/issue9/webview"
+)
+
+// 前端的 ArrayBuffer 和 TypedArray 等以 base64 编码，对应 Go 中的 []byte。
+const jsonJS = `{
+	encode: function(value) {
+		return JSON.stringify(value === undefined ? null : value, function(k, v) {
+			return (v instanceof ArrayBuffer || ArrayBuffer.isView(v)) ? window._rpc.base64(v) : v;
+		});
+	},
+	decode: function(data) { return JSON.parse(data); },
+}`
+
+type jsonCodec struct{}
+
+var jsonInst = &jsonCodec{}
+
+// JSON 采用 encoding/json 编码
+//
+// 这也是默认的编码方式。
+//
+// 返回的对象同时实现了 [webview.StrictCodec] 和 [webview.JSONCodec]。
+func JSON() webview.Codec { return jsonInst }
+
+func (c *jsonCodec) Marshal(v interface{}) ([]byte, error) { return json.Marshal(v) }
+
+func (c *jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+func (c *jsonCodec) UnmarshalStrict(data []byte, v interface{}) error {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.DisallowUnknownFields()
+	d.UseNumber()
+	if err := d.Decode(v); err != nil {
+		return err
+	}
+	if _, err := d.Token(); err != io.EOF {
+		return errors.New("invalid data after top-level value")
+	}
+	return nil
+}
+
+func (c *jsonCodec) JS() string { return jsonJS }
+
+func (c *jsonCodec) IsJSON() bool { return true }
@@ -0,0 +1,44 @@
+// SPDX-License-Identifier: MIT
+
+package codec
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/issue9/assert/v3"
+
+	"github.com/issue9/webview"
+)
+
+func TestJSON(t *testing.T) {
+	a := assert.New(t, false)
+	c := JSON()
+
+	data, err := c.Marshal(&object{Base: &Base{ID: 1}, Tags: []string{"t"}})
+	a.NotError(err).Equal(string(data), `{"id":1,"Embedded":"","Tags":["t"]}`)
+
+	obj := &object{}
+	a.NotError(c.Unmarshal(data, obj)).
+		Equal(obj.ID, 1).
+		Equal(obj.Tags, []string{"t"})
+
+	a.Contains(c.JS(), "encode:").Contains(c.JS(), "decode:")
+}
+
+func TestJSON_UnmarshalStrict(t *testing.T) {
+	a := assert.New(t, false)
+	c := JSON().(webview.StrictCodec)
+
+	obj := &object{}
+	a.NotError(c.UnmarshalStrict([]byte(`{"id":1,"Tags":["t"]} `), obj)).
+		Equal(obj.ID, 1)
+
+	a.Error(c.UnmarshalStrict([]byte(`{"id":1,"tag":["t"]}`), obj)).
+		Error(c.UnmarshalStrict([]byte(`{"id":1} {}`), obj)).
+		Error(c.UnmarshalStrict([]byte(`{"id":1}}`), obj))
+
+	var v interface{}
+	a.NotError(c.UnmarshalStrict([]byte(`{"n":12345678901234567890}`), &v)).
+		Equal(v, map[string]interface{}{"n": json.Number("12345678901234567890")})
+}
@@ -0,0 +1,181 @@
+// SPDX-License-Identifier: MIT
+
+package codec
+
+import (
+	"encoding/base64"
+	"reflect"
+
+	"github.com/issue9/webview"
+)
+
+// 前端的 MessagePack 实现
+//
+// 只实现了 Go 端会用到的类型，不支持扩展类型。
+// 编码时，整数在 [-2^31, 2^32) 之外的以 float64 编码，与 JS 中 number 的精度相同；
+// 解码时，Go 编码的 uint64 和 int64（0xcf 和 0xd3）转换为 number，超出 2^53 的部分会丢失精度。
+const msgpackJS = `(function() {
+	var utf8 = new TextEncoder(), utf8Decoder = new TextDecoder();
+	var encode = function(value) {
+		var buf = new Uint8Array(64), view = new DataView(buf.buffer), off = 0;
+		var reserve = function(n) {
+			if (off + n <= buf.length) { return; }
+			var b = new Uint8Array(Math.max(buf.length * 2, off + n));
+			b.set(buf);
+			buf = b;
+			view = new DataView(buf.buffer);
+		};
+		var u8 = function(v) { reserve(1); buf[off++] = v; };
+		var u16 = function(v) { reserve(2); view.setUint16(off, v); off += 2; };
+		var u32 = function(v) { reserve(4); view.setUint32(off, v); off += 4; };
+		var raw = function(b) { reserve(b.length); buf.set(b, off); off += b.length; };
+		var head = function(n, fix, fixMax, c8, c16, c32) {
+			if (fix >= 0 && n < fixMax) { u8(fix | n); }
+			else if (c8 >= 0 && n < 0x100) { u8(c8); u8(n); }
+			else if (n < 0x10000) { u8(c16); u16(n); }
+			else { u8(c32); u32(n); }
+		};
+		var write = function(v) {
+			if (v && typeof v.toJSON === "function") { v = v.toJSON(); }
+			switch (typeof v) {
+			case "boolean":
+				u8(v ? 0xc3 : 0xc2);
+				return;
+			case "bigint":
+				v = Number(v); // fallthrough
+			case "number":
+				if (!Number.isInteger(v) || v < -0x80000000 || v > 0xffffffff) {
+					u8(0xcb); reserve(8); view.setFloat64(off, v); off += 8;
+				} else if (v >= 0) {
+					if (v < 0x80) { u8(v); }
+					else if (v < 0x100) { u8(0xcc); u8(v); }
+					else if (v < 0x10000) { u8(0xcd); u16(v); }
+					else { u8(0xce); u32(v); }
+				} else {
+					if (v >= -32) { u8(v & 0xff); }
+					else if (v >= -0x80) { u8(0xd0); u8(v & 0xff); }
+					else if (v >= -0x8000) { u8(0xd1); u16(v & 0xffff); }
+					else { u8(0xd2); u32(v >>> 0); }
+				}
+				return;
+			case "string":
+				var s = utf8.encode(v);
+				head(s.length, 0xa0, 32, 0xd9, 0xda, 0xdb);
+				raw(s);
+				return;
+			case "object":
+				if (v === null) { break; }
+				if (v instanceof ArrayBuffer || ArrayBuffer.isView(v)) {
+					var b = v instanceof ArrayBuffer ? new Uint8Array(v) : new Uint8Array(v.buffer, v.byteOffset, v.byteLength);
+					head(b.length, -1, 0, 0xc4, 0xc5, 0xc6);
+					raw(b);
+				} else if (Array.isArray(v)) {
+					head(v.length, 0x90, 16, -1, 0xdc, 0xdd);
+					v.forEach(write);
+				} else {
+					var keys = Object.keys(v).filter(function(k) {
+						return v[k] !== undefined && typeof v[k] !== "function" && typeof v[k] !== "symbol";
+					});
+					head(keys.length, 0x80, 16, -1, 0xde, 0xdf);
+					keys.forEach(function(k) { write(k); write(v[k]); });
+				}
+				return;
+			}
+			u8(0xc0); // null、undefined、function 等
+		};
+		write(value);
+		return window._rpc.base64(buf.subarray(0, off));
+	};
+	var decode = function(data) {
+		var buf = window._rpc.bytes(data), view = new DataView(buf.buffer), off = 0;
+		var str = function(n) { var v = utf8Decoder.decode(buf.subarray(off, off + n)); off += n; return v; };
+		var bin = function(n) { var v = buf.slice(off, off + n); off += n; return v; };
+		var arr = function(n) { var v = []; for (var i = 0; i < n; i++) { v.push(read()); } return v; };
+		var map = function(n) { var v = {}; for (var i = 0; i < n; i++) { var k = read(); v[k] = read(); } return v; };
+		var uint = function(n) {
+			var v = n === 1 ? view.getUint8(off) : n === 2 ? view.getUint16(off) : view.getUint32(off);
+			off += n;
+			return v;
+		};
+		var read = function() {
+			var c = buf[off++], v;
+			if (c < 0x80) { return c; }
+			if (c < 0x90) { return map(c & 0x0f); }
+			if (c < 0xa0) { return arr(c & 0x0f); }
+			if (c < 0xc0) { return str(c & 0x1f); }
+			if (c >= 0xe0) { return c - 0x100; }
+			switch (c) {
+			case 0xc0: return null;
+			case 0xc2: return false;
+			case 0xc3: return true;
+			case 0xc4: return bin(uint(1));
+			case 0xc5: return bin(uint(2));
+			case 0xc6: return bin(uint(4));
+			case 0xca: v = view.getFloat32(off); off += 4; return v;
+			case 0xcb: v = view.getFloat64(off); off += 8; return v;
+			case 0xcc: return uint(1);
+			case 0xcd: return uint(2);
+			case 0xce: return uint(4);
+			case 0xcf: v = view.getUint32(off) * 0x100000000 + view.getUint32(off + 4); off += 8; return v;
+			case 0xd0: v = view.getInt8(off); off += 1; return v;
+			case 0xd1: v = view.getInt16(off); off += 2; return v;
+			case 0xd2: v = view.getInt32(off); off += 4; return v;
+			case 0xd3: v = view.getInt32(off) * 0x100000000 + view.getUint32(off + 4); off += 8; return v;
+			case 0xd9: return str(uint(1));
+			case 0xda: return str(uint(2));
+			case 0xdb: return str(uint(4));
+			case 0xdc: return arr(uint(2));
+			case 0xdd: return arr(uint(4));
+			case 0xde: return map(uint(2));
+			case 0xdf: return map(uint(4));
+			}
+			throw new Error("msgpack: invalid type 0x" + c.toString(16));
+		};
+		return read();
+	};
+	return {encode: encode, decode: decode};
+})()`
+
+type msgpackCodec struct{}
+
+var msgpackInst = &msgpackCodec{}
+
+// MessagePack 采用 MessagePack 编码
+//
+// 编码后的数据以 base64 的形式在前后端之间传递。
+// 类型的处理规则与 encoding/json 相同，[]byte 被编码为二进制类型，在前端为 Uint8Array，
+// 实现了 json.Marshaler 和 json.Unmarshaler 的类型会经由 JSON 中转。
+// 不支持 MessagePack 的扩展类型，也不支持 json 标签的 string 选项。
+//
+// 返回的对象同时实现了 [webview.StrictCodec]。
+func MessagePack() webview.Codec { return msgpackInst }
+
+func (c *msgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	e := &encoder{buf: make([]byte, 0, 64)}
+	if err := e.encode(reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, base64.StdEncoding.EncodedLen(len(e.buf)))
+	base64.StdEncoding.Encode(data, e.buf)
+	return data, nil
+}
+
+func (c *msgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	return c.unmarshal(data, v, false)
+}
+
+func (c *msgpackCodec) UnmarshalStrict(data []byte, v interface{}) error {
+	return c.unmarshal(data, v, true)
+}
+
+func (c *msgpackCodec) unmarshal(data []byte, v interface{}, strict bool) error {
+	buf := make([]byte, base64.StdEncoding.DecodedLen(len(data)))
+	n, err := base64.StdEncoding.Decode(buf, data)
+	if err != nil {
+		return err
+	}
+	return unmarshal(buf[:n], v, strict)
+}
+
+func (c *msgpackCodec) JS() string { return msgpackJS }
@@ -0,0 +1,278 @@
+// SPDX-License-Identifier: MIT
+
+package codec
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v3"
+
+	"github.com/issue9/webview"
+)
+
+type user struct {
+	Base
+	Email   string            `json:"email,omitempty"`
+	Age     uint8             `json:"age"`
+	Score   float64           `json:"score"`
+	Avatar  []byte            `json:"avatar"`
+	Tags    []string          `json:"tags"`
+	Meta    map[string]int    `json:"meta"`
+	Groups  map[int]string    `json:"groups"`
+	Created time.Time         `json:"created"`
+	Parent  *user             `json:"parent"`
+	Any     interface{}       `json:"any"`
+	Raw     json.RawMessage   `json:"raw"`
+	Point   [2]int            `json:"point"`
+	Extra   map[string]string `json:"extra,omitempty"`
+}
+
+func TestMessagePack(t *testing.T) {
+	a := assert.New(t, false)
+	c := MessagePack()
+
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	u := &user{
+		Base:    Base{ID: 1, Name: "n"},
+		Age:     18,
+		Score:   1.5,
+		Avatar:  []byte{1, 2, 3},
+		Tags:    []string{"a", "b"},
+		Meta:    map[string]int{"k": -1},
+		Groups:  map[int]string{1: "g"},
+		Created: created,
+		Parent:  &user{Base: Base{ID: 2}},
+		Any:     map[string]interface{}{"x": 1.5},
+		Raw:     json.RawMessage(`{"k":[1,2]}`),
+		Point:   [2]int{3, 4},
+	}
+
+	data, err := c.Marshal(u)
+	a.NotError(err)
+	_, err = base64.StdEncoding.DecodeString(string(data))
+	a.NotError(err)
+
+	v := &user{}
+	a.NotError(c.Unmarshal(data, v))
+	a.Equal(v.ID, 1).
+		Equal(v.Name, "n").
+		Empty(v.Email).
+		Equal(v.Age, 18).
+		Equal(v.Score, 1.5).
+		Equal(v.Avatar, []byte{1, 2, 3}).
+		Equal(v.Tags, []string{"a", "b"}).
+		Equal(v.Meta, map[string]int{"k": -1}).
+		Equal(v.Groups, map[int]string{1: "g"}).
+		True(v.Created.Equal(created)).
+		Equal(v.Parent.ID, 2).
+		Nil(v.Parent.Parent).
+		Equal(v.Any, map[string]interface{}{"x": 1.5}).
+		Equal(string(v.Raw), `{"k":[1,2]}`).
+		Equal(v.Point, [2]int{3, 4}).
+		Nil(v.Extra)
+
+	// 通用类型
+	var x interface{}
+	a.NotError(c.Unmarshal(data, &x))
+	m, ok := x.(map[string]interface{})
+	a.True(ok).
+		Equal(m["id"], 1.0).
+		Equal(m["avatar"], []byte{1, 2, 3}).
+		Equal(m["created"], created.Format(time.RFC3339Nano)).
+		NotContains(m, "email")
+
+	// 非指针
+	a.Error(c.Unmarshal(data, user{}))
+
+	// 无法编码的类型
+	_, err = c.Marshal(func() {})
+	a.Error(err)
+	_, err = c.Marshal(map[float64]int{1: 1})
+	a.Error(err)
+
+	a.Contains(c.JS(), "encode: encode")
+}
+
+func TestMessagePack_UnmarshalStrict(t *testing.T) {
+	a := assert.New(t, false)
+	c := MessagePack().(webview.StrictCodec)
+
+	data, err := c.Marshal(map[string]interface{}{"id": 1, "name": "n"})
+	a.NotError(err)
+	b := &Base{}
+	a.NotError(c.UnmarshalStrict(data, b)).Equal(b, &Base{ID: 1, Name: "n"})
+
+	data, err = c.Marshal(map[string]interface{}{"id": 1, "nam": "n"})
+	a.NotError(err)
+	a.NotError(c.Unmarshal(data, b)).
+		Error(c.UnmarshalStrict(data, b))
+
+	data, err = c.Marshal([]interface{}{1, -2, 1.5, uint64(math.MaxUint64)})
+	a.NotError(err)
+	var v interface{}
+	a.NotError(c.UnmarshalStrict(data, &v)).
+		Equal(v, []interface{}{json.Number("1"), json.Number("-2"), json.Number("1.5"), json.Number("18446744073709551615")})
+}
+
+func TestMessagePack_numbers(t *testing.T) {
+	a := assert.New(t, false)
+
+	for _, i := range []int64{0, 1, 127, 128, 255, 256, 65535, 65536, math.MaxUint32, math.MaxUint32 + 1, math.MaxInt64,
+		-1, -32, -33, -128, -129, -32768, -32769, math.MinInt32, math.MinInt32 - 1, math.MinInt64} {
+		e := &encoder{}
+		e.int(i)
+		var v int64
+		a.NotError(unmarshal(e.buf, &v, false)).Equal(v, i)
+	}
+
+	e := &encoder{}
+	e.uint(math.MaxUint64)
+	var u uint64
+	a.NotError(unmarshal(e.buf, &u, false)).Equal(u, uint64(math.MaxUint64))
+
+	// 溢出
+	var i8 int8
+	a.Error(unmarshal(e.buf, &i8, false))
+	e = &encoder{}
+	e.int(-1)
+	a.Error(unmarshal(e.buf, &u, false))
+
+	// 浮点数转换为整数
+	e = &encoder{}
+	e.float(5)
+	a.NotError(unmarshal(e.buf, &i8, false)).Equal(i8, 5)
+	e = &encoder{}
+	e.float(5.5)
+	a.Error(unmarshal(e.buf, &i8, false))
+
+	var f32 float32
+	e = &encoder{}
+	a.NotError(e.encode(reflect.ValueOf(float32(1.25))))
+	a.Equal(e.buf[0], 0xca).
+		NotError(unmarshal(e.buf, &f32, false)).Equal(f32, float32(1.25))
+}
+
+func TestMessagePack_length(t *testing.T) {
+	a := assert.New(t, false)
+
+	for _, n := range []int{0, 31, 32, 255, 256, 65535, 65536} {
+		s := strings.Repeat("x", n)
+		e := &encoder{}
+		e.str(s)
+		var v string
+		a.NotError(unmarshal(e.buf, &v, false)).Equal(v, s)
+
+		arr := make([]int, n)
+		e = &encoder{}
+		a.NotError(e.encode(reflect.ValueOf(arr)))
+		var vs []int
+		a.NotError(unmarshal(e.buf, &vs, false)).Length(vs, n)
+	}
+
+	// 长度超出数据
+	a.Error(unmarshal([]byte{0xdd, 0xff, 0xff, 0xff, 0xff}, new([]int), false))
+	a.Error(unmarshal([]byte{0xdb, 0xff, 0xff, 0xff, 0xff}, new(string), false))
+	a.Error(unmarshal([]byte{}, new(string), false))
+
+	// 多余的数据
+	a.Error(unmarshal([]byte{0x01, 0x02}, new(int), false))
+
+	// 类型不匹配
+	a.Error(unmarshal([]byte{0xc3}, new(string), false))
+	a.Error(unmarshal([]byte{0x91, 0x01}, new(string), false))
+}
+
+func TestMessagePack_depth(t *testing.T) {
+	a := assert.New(t, false)
+
+	nested := func(n int) []byte { return append(bytes.Repeat([]byte{0x91}, n), 0xc0) }
+
+	var v interface{}
+	a.NotError(unmarshal(nested(maxDepth), &v, false))
+
+	type node []node
+	var n node
+	a.NotError(unmarshal(nested(maxDepth), &n, false))
+
+	// 超出嵌套层数，不能导致栈溢出。
+	for _, data := range [][]byte{nested(maxDepth + 1), nested(5 << 20)} {
+		a.ErrorIs(unmarshal(data, &v, false), errMaxDepth).
+			ErrorIs(unmarshal(data, &n, false), errMaxDepth).
+			ErrorIs(unmarshal(data, &[]json.RawMessage{}, false), errMaxDepth)
+	}
+
+	// map 同样受限制
+	data := append(bytes.Repeat([]byte{0x81, 0xa1, 'k'}, maxDepth+1), 0xc0)
+	a.ErrorIs(unmarshal(data, &v, false), errMaxDepth)
+}
+
+func TestMessagePack_cycle(t *testing.T) {
+	a := assert.New(t, false)
+	c := MessagePack()
+
+	type node struct {
+		Next *node
+	}
+	n := &node{}
+	n.Next = n
+
+	m := map[string]interface{}{}
+	m["m"] = m
+
+	s := []interface{}{nil}
+	s[0] = s
+
+	for _, v := range []interface{}{n, m, s} {
+		_, err := c.Marshal(v)
+		var uve *json.UnsupportedValueError
+		a.True(errors.As(err, &uve), err)
+	}
+
+	// 嵌套层数较多，但是没有循环引用。
+	list := &node{}
+	for i := 0; i < 2*startDetectingCyclesAfter; i++ {
+		list = &node{Next: list}
+	}
+	_, err := c.Marshal(list)
+	a.NotError(err)
+
+	// 同一对象多次出现不属于循环引用
+	shared := []int{1}
+	deep := interface{}(shared)
+	for i := 0; i < 2*startDetectingCyclesAfter; i++ {
+		deep = []interface{}{shared, deep}
+	}
+	_, err = c.Marshal(deep)
+	a.NotError(err)
+}
+
+// 由前端的 encode 生成的数据
+func TestMessagePack_js(t *testing.T) {
+	a := assert.New(t, false)
+	c := MessagePack()
+
+	var i int
+	a.NotError(c.Unmarshal([]byte("0v/+7pA="), &i)).Equal(i, -70000)
+
+	var bs []byte
+	a.NotError(c.Unmarshal([]byte("xAMBAgM="), &bs)).Equal(bs, []byte{1, 2, 3})
+
+	var obj struct {
+		A []interface{} `json:"a"`
+	}
+	a.NotError(c.Unmarshal([]byte("gaFhk8s/+AAAAAAAAMDD"), &obj)).
+		Equal(obj.A, []interface{}{1.5, nil, true})
+
+	var cb struct {
+		ID *int `json:"$callback"`
+	}
+	a.NotError(c.Unmarshal([]byte("gakkY2FsbGJhY2sB"), &cb)).Equal(*cb.ID, 1)
+}
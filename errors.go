@@ -2,11 +2,26 @@
 
 package webview
 
-import "errors"
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
 
 var (
 	errOnlyFuncCanBound      = errors.New("only functions can be bound")
 	errBindFuncReturnInvalid = errors.New("bind function may only return a value or value+error")
+	errBindFuncParamInvalid  = errors.New("bind function has an invalid parameter")
+	errMethodNotFound        = errors.New("method not found")
+	errBindObjectNoMethod    = errors.New("object has no method to bind")
+	errBindObjectNil         = errors.New("object is a nil pointer")
+	errNameConflict          = errors.New("name conflicts with a bound function or namespace")
+	errCallbackReleased      = errors.New("callback has been released")
+	errInternal              = errors.New("internal error")
+	errOriginNotAllowed      = errors.New("origin not allowed")
+	errInvalidOptions        = errors.New("invalid window options")
+	errClosed                = errors.New("app closed")
+	errBusy                  = errors.New("too many pending calls")
 )
 
 // ErrOnlyFuncCanBound 表示绑定的对象不是方法
@@ -21,3 +36,144 @@ func ErrOnlyFuncCanBound() error { return errOnlyFuncCanBound }
 //
 // 其它情况会返回此错误。
 func ErrBindFuncReturnInvalid() error { return errBindFuncReturnInvalid }
+
+// ErrBindFuncParamInvalid 表示绑定方法的参数类型不符合要求
+//
+// *CallInfo、context.Context 和 Stream 由 Binder 自动填充，只能位于最前面：
+// 前两者以任意顺序排在最前，各自最多一个，Stream 则在其后。
+// 在其它位置出现时会返回此错误，实际返回的错误会包含参数信息，需要采用 errors.Is 进行判断。
+func ErrBindFuncParamInvalid() error { return errBindFuncParamInvalid }
+
+// ErrBindObjectNoMethod 表示通过 BindObject 绑定的对象没有可绑定的方法
+func ErrBindObjectNoMethod() error { return errBindObjectNoMethod }
+
+// ErrBindObjectNil 表示通过 BindObject 绑定的对象是值为 nil 的指针
+func ErrBindObjectNil() error { return errBindObjectNil }
+
+// ErrNameConflict 表示绑定的名称与已有的方法或是命名空间冲突
+//
+// Bind 的方法名不能与 BindObject 的命名空间相同，反之亦然。
+func ErrNameConflict() error { return errNameConflict }
+
+// ErrCallbackReleased 表示前端的回调函数已经被释放
+//
+// 调用结束时未被 [Callback.Keep] 保留，调用 [Callback.Release] 或是页面跳转之后，回调函数都将不再可用。
+func ErrCallbackReleased() error { return errCallbackReleased }
+
+// ErrMethodNotFound 表示前端调用了未绑定的方法
+//
+// 前端的 Promise 会以此错误拒绝，对应的错误代码为 [ErrorCodeMethodNotFound]。
+// 实际返回的错误会包含方法名，需要采用 errors.Is 进行判断。
+func ErrMethodNotFound() error { return errMethodNotFound }
+
+// ErrInternal 表示绑定的方法在执行过程中发生了 panic
+//
+// 前端的 Promise 会以此错误拒绝，对应的错误代码为 [ErrorCodeInternal]，
+// panic 的具体内容和调用栈只会输出到日志，不会传递给前端。
+func ErrInternal() error { return errInternal }
+
+// ErrOriginNotAllowed 表示调用的来源不在允许的列表中
+//
+// 前端的 Promise 会以此错误拒绝，对应的错误代码为 [ErrorCodeForbidden]，
+// 实际返回的错误会包含方法名，需要采用 errors.Is 进行判断。
+func ErrOriginNotAllowed() error { return errOriginNotAllowed }
+
+// ErrInvalidOptions 表示 [Application.NewWindow] 的参数不是当前平台的选项类型
+func ErrInvalidOptions() error { return errInvalidOptions }
+
+// ErrClosed 表示 [App] 已经关闭
+//
+// 由 [App.DispatchSync] 返回，表示传入的函数未被执行。
+func ErrClosed() error { return errClosed }
+
+// ErrBusy 表示等待执行的调用过多
+//
+// 前端的 Promise 会以此错误拒绝，对应的错误代码为 [ErrorCodeBusy]。
+func ErrBusy() error { return errBusy }
+
+// 由 webview 自身产生的错误代码
+//
+// 前端得到的错误对象中的 code 字段可能是以下值，也可以是 [CodeError] 返回的值。
+const (
+	ErrorCodeCanceled       = "canceled"         // 调用被取消
+	ErrorCodeTimeout        = "timeout"          // 调用超时
+	ErrorCodeMethodNotFound = "method_not_found" // 调用的方法不存在
+	ErrorCodeInternal       = "internal"         // 绑定的方法发生了 panic
+	ErrorCodeInvalidParams  = "invalid_params"   // 参数未通过验证
+	ErrorCodeForbidden      = "forbidden"        // 调用的来源不被允许
+	ErrorCodeBusy           = "busy"             // 等待执行的调用过多
+)
+
+// CodeError 带错误代码的错误
+//
+// 绑定的方法返回的错误如果实现了此接口，前端得到的错误对象中会包含 code 字段。
+// 查找时采用 errors.As，所以被包装的错误同样有效。
+type CodeError interface {
+	error
+	ErrorCode() string
+}
+
+// DataError 带附加数据的错误
+//
+// 绑定的方法返回的错误如果实现了此接口，前端得到的错误对象中会包含 data 字段，
+// 其值由 ErrorData 的返回值经 Codec 编码而来。查找时采用 errors.As。
+type DataError interface {
+	error
+	ErrorData() interface{}
+}
+
+type codeError struct {
+	code string
+	msg  string
+	data interface{}
+}
+
+// NewError 声明同时实现了 [CodeError] 和 [DataError] 的错误对象
+func NewError(code, msg string, data interface{}) error {
+	return &codeError{code: code, msg: msg, data: data}
+}
+
+func (err *codeError) Error() string { return err.msg }
+
+func (err *codeError) ErrorCode() string { return err.code }
+
+func (err *codeError) ErrorData() interface{} { return err.data }
+
+// FieldError 单个字段的验证错误
+type FieldError struct {
+	Param   int    `json:"param"`           // 参数的索引，不包含由 Binder 填充的 *CallInfo、context.Context 和 Stream。
+	Field   string `json:"field,omitempty"` // 字段的路径，以 json 名称表示，比如 user.tags[0]，为空表示参数本身。
+	Rule    string `json:"rule"`            // 未通过的规则，由 Validator 返回的错误为 validate，无法解码的参数为 decode。
+	Message string `json:"message"`
+}
+
+func (err *FieldError) Error() string {
+	name := "arg" + strconv.Itoa(err.Param)
+	if err.Field != "" {
+		name += "." + err.Field
+	}
+	return name + " " + err.Message
+}
+
+// ValidationError 参数的验证错误
+//
+// 同时实现了 [CodeError] 和 [DataError]，前端得到的错误代码为 [ErrorCodeInvalidParams]，
+// data 字段为 Fields 的内容。
+//
+// [Validator] 也可以返回此错误以报告多个字段的错误，
+// 其中 Field 为相对于当前值的路径，Param 会被忽略。
+type ValidationError struct {
+	Fields []*FieldError
+}
+
+func (err *ValidationError) Error() string {
+	msgs := make([]string, 0, len(err.Fields))
+	for _, f := range err.Fields {
+		msgs = append(msgs, f.Error())
+	}
+	return "invalid params: " + strings.Join(msgs, "; ")
+}
+
+func (err *ValidationError) ErrorCode() string { return ErrorCodeInvalidParams }
+
+func (err *ValidationError) ErrorData() interface{} { return err.Fields }
@@ -0,0 +1,409 @@
+// SPDX-License-Identifier: MIT
+
+// Package dts 根据绑定方法的类型生成 TypeScript 的声明文件
+package dts
+
+import (
+	"bytes"
+	"context"
+	"encoding"
+	"encoding/json"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/issue9/webview"
+	"github.com/issue9/webview/internal/structs"
+)
+
+var (
+	errorType         = reflect.TypeOf((*error)(nil)).Elem()
+	contextType       = reflect.TypeOf((*context.Context)(nil)).Elem()
+	callInfoType      = reflect.TypeOf((*webview.CallInfo)(nil))
+	streamType        = reflect.TypeOf((*webview.Stream)(nil)).Elem()
+	callbackType      = reflect.TypeOf((*webview.Callback)(nil)).Elem()
+	timeType          = reflect.TypeOf(time.Time{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// 生成内容的头部，包含了运行时相关的声明。
+const header = `// 由 github.com/issue9/webview 生成，请勿手动修改。
+
+interface RPCPromise<T> extends Promise<T> {
+	cancel(): void;
+}
+
+interface RPCStream<T> extends AsyncIterable<T> {
+	next(): Promise<IteratorResult<T>>;
+	each(fn: (item: T) => void): Promise<void>;
+	cancel(): void;
+}
+
+interface RPCOptions {
+	signal?: AbortSignal;
+	timeout?: number;
+}
+
+type RPCFunc<F extends (...args: any[]) => any> = F & {
+	withOptions(options: RPCOptions): F;
+};
+
+interface RPCError {
+	message: string;
+	code?: string;
+	data?: any;
+}
+
+declare namespace webview {
+	function on(event: string, listener: (payload: any) => void): void;
+	function once(event: string, listener: (payload: any) => void): void;
+	function off(event: string, listener?: (payload: any) => void): void;
+	function emit(event: string, payload?: any): void;
+}
+`
+
+// JavaScript 的保留字，不能作为 declare 的名称。
+var reserved = map[string]struct{}{}
+
+func init() {
+	for _, w := range strings.Fields(`break case catch class const continue debugger default delete do else enum
+		export extends false finally for function if import in instanceof new null return super switch this throw
+		true try typeof var void while with implements interface let package private protected public static yield`) {
+		reserved[w] = struct{}{}
+	}
+}
+
+// Generator TypeScript 声明的生成器
+type Generator struct {
+	funcs   map[string]reflect.Type
+	objects map[string]map[string]reflect.Type
+
+	types map[reflect.Type]string // 已经声明的类型及其在 TypeScript 中的名称
+	names map[string]struct{}     // 已经使用的类型名称
+	decls []string                // 类型的声明
+}
+
+func New() *Generator {
+	return &Generator{
+		funcs:   make(map[string]reflect.Type, 10),
+		objects: make(map[string]map[string]reflect.Type, 10),
+		types:   make(map[reflect.Type]string, 10),
+		names: map[string]struct{}{ // 已经在 header 中使用的名称
+			"RPCPromise": {},
+			"RPCStream":  {},
+			"RPCError":   {},
+			"RPCOptions": {},
+			"RPCFunc":    {},
+			"Window":     {},
+		},
+	}
+}
+
+// Func 添加 window[name] 方法的声明
+//
+// t 必须是函数类型，且符合 Bind 的要求。
+func (g *Generator) Func(name string, t reflect.Type) { g.funcs[name] = t }
+
+// Method 添加 window[namespace][name] 方法的声明
+//
+// t 必须是函数类型，且符合 Bind 的要求。
+func (g *Generator) Method(namespace, name string, t reflect.Type) {
+	methods, found := g.objects[namespace]
+	if !found {
+		methods = make(map[string]reflect.Type, 10)
+		g.objects[namespace] = methods
+	}
+	methods[name] = t
+}
+
+// WriteTo 将声明写入 w
+func (g *Generator) WriteTo(w io.Writer) (int64, error) {
+	decls := &bytes.Buffer{}  // 采用 declare 声明的内容
+	window := &bytes.Buffer{} // 名称不是合法标志符的内容，只能声明在 Window 接口上。
+
+	funcs := make([]string, 0, len(g.funcs))
+	for name := range g.funcs {
+		funcs = append(funcs, name)
+	}
+	sort.Strings(funcs)
+	for _, name := range funcs {
+		sig := g.signature(g.funcs[name])
+		if isIdentifier(name) {
+			decls.WriteString("declare const " + name + ": " + sig + ";\n")
+		} else {
+			window.WriteString("\t" + strconv.Quote(name) + ": " + sig + ";\n")
+		}
+	}
+
+	namespaces := make([]string, 0, len(g.objects))
+	for ns := range g.objects {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+	for _, ns := range namespaces {
+		methods := g.objects[ns]
+		names := make([]string, 0, len(methods))
+		for name := range methods {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		obj := &bytes.Buffer{}
+		obj.WriteString("{\n")
+		for _, name := range names {
+			obj.WriteString("\t" + propertyName(name) + ": " + g.signature(methods[name]) + ";\n")
+		}
+		obj.WriteString("}")
+
+		if isIdentifier(ns) {
+			decls.WriteString("declare const " + ns + ": " + obj.String() + ";\n")
+		} else {
+			window.WriteString("\t" + strconv.Quote(ns) + ": " + strings.ReplaceAll(obj.String(), "\n", "\n\t") + ";\n")
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteString(header)
+	for _, decl := range g.decls {
+		buf.WriteString("\n" + decl)
+	}
+	if decls.Len() > 0 {
+		buf.WriteString("\n")
+		buf.Write(decls.Bytes())
+	}
+	if window.Len() > 0 {
+		buf.WriteString("\ninterface Window {\n")
+		buf.Write(window.Bytes())
+		buf.WriteString("}\n")
+	}
+
+	return buf.WriteTo(w)
+}
+
+// 生成函数在前端的类型
+//
+// 前端的函数都带有 withOptions 方法，所以统一声明为 RPCFunc。
+func (g *Generator) signature(t reflect.Type) string {
+	return "RPCFunc<" + g.arrow(t) + ">"
+}
+
+// InjectedParams 函数 t 开头由 Binder 自动填充的参数数量
+//
+// *webview.CallInfo 和 context.Context 以任意顺序位于最前面，各自最多一个，
+// 之后可以是 webview.Stream，stream 表示是否包含 webview.Stream。
+func InjectedParams(t reflect.Type) (n int, stream bool) {
+	info, ctx := false, false
+	for ; n < t.NumIn(); n++ {
+		if in := t.In(n); in == callInfoType && !info {
+			info = true
+		} else if in == contextType && !ctx {
+			ctx = true
+		} else {
+			break
+		}
+	}
+
+	if n < t.NumIn() && t.In(n) == streamType {
+		return n + 1, true
+	}
+	return n, false
+}
+
+// 生成函数的箭头函数形式
+func (g *Generator) arrow(t reflect.Type) string {
+	in, stream := InjectedParams(t)
+
+	required := t.NumIn() // 末尾的指针参数可以省略
+	if t.IsVariadic() {
+		required--
+	}
+	for required > in && t.In(required-1).Kind() == reflect.Ptr {
+		required--
+	}
+
+	params := make([]string, 0, t.NumIn())
+	for i := in; i < t.NumIn(); i++ {
+		name := "arg" + strconv.Itoa(i-in)
+		switch {
+		case t.IsVariadic() && i == t.NumIn()-1:
+			params = append(params, "..."+name+": "+arrayOf(g.tsType(t.In(i).Elem())))
+		case i >= required:
+			params = append(params, name+"?: "+g.paramType(t.In(i)))
+		default:
+			params = append(params, name+": "+g.paramType(t.In(i)))
+		}
+	}
+
+	if stream { // 通过 Stream 推送的数据，无法确定其类型。
+		return "(" + strings.Join(params, ", ") + ") => RPCStream<any>"
+	}
+
+	ret := "void"
+	if t.NumOut() > 0 && !t.Out(0).Implements(errorType) {
+		ret = g.resultType(t.Out(0))
+	}
+
+	if t.NumOut() > 0 && t.Out(0).Kind() == reflect.Chan && t.Out(0).ChanDir() == reflect.RecvDir {
+		return "(" + strings.Join(params, ", ") + ") => RPCStream<" + g.resultType(t.Out(0).Elem()) + ">"
+	}
+	return "(" + strings.Join(params, ", ") + ") => RPCPromise<" + ret + ">"
+}
+
+// 作为参数时 t 在 TypeScript 中对应的类型
+//
+// 前端的 ArrayBuffer 和 TypedArray 等都可以作为 []byte 传递。
+func (g *Generator) paramType(t reflect.Type) string {
+	if isBytes(t) {
+		return "ArrayBuffer | ArrayBufferView | string"
+	}
+	return g.tsType(t)
+}
+
+// 作为返回值时 t 在 TypeScript 中对应的类型
+//
+// 直接返回的 []byte 在前端被转换为 Uint8Array。
+func (g *Generator) resultType(t reflect.Type) string {
+	if isBytes(t) {
+		return "Uint8Array | null"
+	}
+	return g.tsType(t)
+}
+
+// 返回 t 经 encoding/json 编码之后在 TypeScript 中对应的类型
+func (g *Generator) tsType(t reflect.Type) string {
+	switch {
+	case t == callbackType:
+		return "(...args: any[]) => void"
+	case t == timeType:
+		return "string"
+	case t.Implements(jsonMarshalerType) || reflect.PtrTo(t).Implements(jsonMarshalerType):
+		return "any"
+	case t.Implements(textMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType):
+		return "string"
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Ptr:
+		return g.tsType(t.Elem()) + " | null"
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 { // []byte 被编码为 base64 字符串
+			return "string"
+		}
+		return arrayOf(g.tsType(t.Elem())) + " | null"
+	case reflect.Array:
+		return arrayOf(g.tsType(t.Elem()))
+	case reflect.Map:
+		return "{ [key: string]: " + g.tsType(t.Elem()) + " } | null"
+	case reflect.Struct:
+		if t.Name() == "" {
+			return "{ " + strings.Join(g.fields(t), " ") + " }"
+		}
+		return g.declare(t)
+	default: // interface、func、chan 等
+		return "any"
+	}
+}
+
+// 将结构体声明为 interface 并返回其名称
+func (g *Generator) declare(t reflect.Type) string {
+	if name, found := g.types[t]; found {
+		return name
+	}
+
+	name := strings.Map(func(r rune) rune {
+		if r == '_' || r == '$' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, t.Name())
+	if _, found := g.names[name]; found { // 不同包中的同名类型
+		for i := 2; ; i++ {
+			if n := name + strconv.Itoa(i); !hasKey(g.names, n) {
+				name = n
+				break
+			}
+		}
+	}
+	g.names[name] = struct{}{}
+	g.types[t] = name // 需要在 fields 之前，防止自引用的类型无限递归。
+
+	decl := "interface " + name + " {\n"
+	for _, f := range g.fields(t) {
+		decl += "\t" + f + "\n"
+	}
+	g.decls = append(g.decls, decl+"}\n")
+
+	return name
+}
+
+// 按 encoding/json 的规则生成结构体的字段列表
+func (g *Generator) fields(t reflect.Type) []string {
+	fs := structs.Fields(t)
+	fields := make([]string, 0, len(fs))
+	for _, f := range fs {
+		typ := g.tsType(f.Type)
+		if f.String {
+			typ = "string"
+		}
+
+		optional := ""
+		if f.OmitEmpty {
+			optional = "?"
+		}
+
+		fields = append(fields, propertyName(f.Name)+optional+": "+typ+";")
+	}
+	return fields
+}
+
+func arrayOf(typ string) string {
+	if strings.ContainsAny(typ, " |") {
+		return "Array<" + typ + ">"
+	}
+	return typ + "[]"
+}
+
+func propertyName(name string) string {
+	if isIdentifier(name) {
+		return name
+	}
+	return strconv.Quote(name)
+}
+
+func isIdentifier(name string) bool {
+	if name == "" || hasKey(reserved, name) {
+		return false
+	}
+
+	for i, r := range name {
+		switch {
+		case r == '_' || r == '$' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z'):
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func hasKey(m map[string]struct{}, key string) bool {
+	_, found := m[key]
+	return found
+}
+
+func isBytes(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 &&
+		!t.Implements(jsonMarshalerType) && !t.Implements(textMarshalerType)
+}
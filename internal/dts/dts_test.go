@@ -0,0 +1,135 @@
+// SPDX-License-Identifier: MIT
+
+package dts
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v3"
+
+	"github.com/issue9/webview"
+)
+
+type Base struct {
+	ID int `json:"id"`
+}
+
+type User struct {
+	Base
+	Name     string            `json:"name"`
+	Email    string            `json:"email,omitempty"`
+	Age      int               `json:"age,string"`
+	Created  time.Time         `json:"created"`
+	Parent   *User             `json:"parent"`
+	Tags     []string          `json:"tags"`
+	Avatar   []byte            `json:"avatar"`
+	Meta     map[string]*int   `json:"meta"`
+	Any      interface{}       `json:"any"`
+	Ignored  string            `json:"-"`
+	internal string            // 非导出
+	Inline   struct{ X int }   `json:"inline"`
+	Point    [2]float64        `json:"point"`
+	Groups   map[int][]string  `json:"groups"`
+	Custom   map[string]string `json:"my-custom"`
+}
+
+func TestGenerator(t *testing.T) {
+	a := assert.New(t, false)
+	g := New()
+
+	g.Func("add", reflect.TypeOf(func(int, int) int { return 0 }))
+	g.Func("noop", reflect.TypeOf(func() {}))
+	g.Func("save", reflect.TypeOf(func(context.Context, *User) error { return nil }))
+	g.Func("users", reflect.TypeOf(func(...int) ([]*User, error) { return nil, nil }))
+	g.Func("my-func", reflect.TypeOf(func(string) string { return "" }))
+	g.Func("delete", reflect.TypeOf(func(string) {}))
+	g.Func("lines", reflect.TypeOf(func(context.Context, string) (<-chan string, error) { return nil, nil }))
+	g.Func("scan", reflect.TypeOf(func(string, webview.Callback) {}))
+	g.Func("logs", reflect.TypeOf(func(context.Context, webview.Stream, int) error { return nil }))
+	g.Func("reverse", reflect.TypeOf(func([]byte) ([]byte, error) { return nil, nil }))
+	g.Func("find", reflect.TypeOf(func(*int, string, *int, *string, ...int) {}))
+	g.Func("audit", reflect.TypeOf(func(*webview.CallInfo, context.Context, string) error { return nil }))
+	g.Func("tail", reflect.TypeOf(func(context.Context, *webview.CallInfo, webview.Stream, string) error { return nil }))
+	g.Method("obj", "get", reflect.TypeOf(func() User { return User{} }))
+	g.Method("my-obj", "get-x", reflect.TypeOf(func() bool { return true }))
+
+	buf := &bytes.Buffer{}
+	_, err := g.WriteTo(buf)
+	a.NotError(err)
+	out := buf.String()
+
+	a.True(strings.HasPrefix(out, header))
+	a.Contains(out, `interface User {
+	id: number;
+	name: string;
+	email?: string;
+	age: string;
+	created: string;
+	parent: User | null;
+	tags: string[] | null;
+	avatar: string;
+	meta: { [key: string]: number | null } | null;
+	any: any;
+	inline: { X: number; };
+	point: number[];
+	groups: { [key: string]: string[] | null } | null;
+	"my-custom": { [key: string]: string } | null;
+}
+`)
+	a.NotContains(out, "Ignored").
+		NotContains(out, "internal").
+		NotContains(out, "interface Base")
+
+	a.Contains(out, `
+declare const add: RPCFunc<(arg0: number, arg1: number) => RPCPromise<number>>;
+declare const audit: RPCFunc<(arg0: string) => RPCPromise<void>>;
+declare const find: RPCFunc<(arg0: number | null, arg1: string, arg2?: number | null, arg3?: string | null, ...arg4: number[]) => RPCPromise<void>>;
+declare const lines: RPCFunc<(arg0: string) => RPCStream<string>>;
+declare const logs: RPCFunc<(arg0: number) => RPCStream<any>>;
+declare const noop: RPCFunc<() => RPCPromise<void>>;
+declare const reverse: RPCFunc<(arg0: ArrayBuffer | ArrayBufferView | string) => RPCPromise<Uint8Array | null>>;
+declare const save: RPCFunc<(arg0?: User | null) => RPCPromise<void>>;
+declare const scan: RPCFunc<(arg0: string, arg1: (...args: any[]) => void) => RPCPromise<void>>;
+declare const tail: RPCFunc<(arg0: string) => RPCStream<any>>;
+declare const users: RPCFunc<(...arg0: number[]) => RPCPromise<Array<User | null> | null>>;
+declare const obj: {
+	get: RPCFunc<() => RPCPromise<User>>;
+};
+`)
+
+	a.Contains(out, `
+interface Window {
+	"delete": RPCFunc<(arg0: string) => RPCPromise<void>>;
+	"my-func": RPCFunc<(arg0: string) => RPCPromise<string>>;
+	"my-obj": {
+		"get-x": RPCFunc<() => RPCPromise<boolean>>;
+	};
+}
+`)
+
+	// 嵌入结构体的字段被同名字段覆盖
+	type Account struct {
+		Base
+		ID   string `json:"id"`
+		Name string
+	}
+	g.Func("account", reflect.TypeOf(func() Account { return Account{} }))
+	buf.Reset()
+	_, err = g.WriteTo(buf)
+	a.NotError(err).
+		Contains(buf.String(), "interface Account {\n\tid: string;\n\tName: string;\n}")
+
+	// 同名的类型
+	type User struct{ Name string }
+	g.Func("user2", reflect.TypeOf(func() User { return User{} }))
+	buf.Reset()
+	_, err = g.WriteTo(buf)
+	a.NotError(err).
+		Contains(buf.String(), "interface User2 {\n\tName: string;\n}").
+		Contains(buf.String(), "declare const user2: RPCFunc<() => RPCPromise<User2>>;")
+}
@@ -0,0 +1,136 @@
+// SPDX-License-Identifier: MIT
+
+// Package lifecycle 各平台 webview.Application 的公共部分
+//
+// 负责管理窗口列表以及各个生命周期的回调函数，消息循环由各平台自行实现。
+package lifecycle
+
+import (
+	"sync"
+
+	"github.com/issue9/webview"
+)
+
+// Lifecycle 应用程序的生命周期
+type Lifecycle struct {
+	exit func()
+
+	mux       sync.Mutex
+	windows   []webview.Desktop
+	ready     []func()
+	allClosed []func()
+	quit      []func()
+	running   bool
+	quitting  bool
+}
+
+// New 声明 Lifecycle 对象
+//
+// exit 用于退出消息循环，可能在任意 goroutine 中调用，
+// 也可能在消息循环开始之前调用，此时消息循环在开始之后应该立即退出。
+func New(exit func()) *Lifecycle { return &Lifecycle{exit: exit} }
+
+// Add 添加新创建的窗口
+func (l *Lifecycle) Add(w webview.Desktop) {
+	l.mux.Lock()
+	l.windows = append(l.windows, w)
+	l.mux.Unlock()
+}
+
+// Windows 所有未关闭的窗口
+func (l *Lifecycle) Windows() []webview.Desktop {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	return append(make([]webview.Desktop, 0, len(l.windows)), l.windows...)
+}
+
+func (l *Lifecycle) OnReady(f func()) { l.append(&l.ready, f) }
+
+func (l *Lifecycle) OnAllWindowsClosed(f func()) { l.append(&l.allClosed, f) }
+
+func (l *Lifecycle) OnQuit(f func()) { l.append(&l.quit, f) }
+
+func (l *Lifecycle) append(hooks *[]func(), f func()) {
+	l.mux.Lock()
+	*hooks = append(*hooks, f)
+	l.mux.Unlock()
+}
+
+// Run 执行消息循环 loop
+//
+// 在 loop 之前和之后分别调用 OnReady 和 OnQuit 注册的函数，
+// 如果消息循环已经在运行，则直接返回。
+func (l *Lifecycle) Run(loop func()) {
+	l.mux.Lock()
+	if l.running {
+		l.mux.Unlock()
+		return
+	}
+	l.running = true
+	ready := l.ready
+	l.mux.Unlock()
+
+	call(ready)
+	loop()
+
+	l.mux.Lock()
+	l.running = false
+	l.quitting = false
+	quit := l.quit
+	l.mux.Unlock()
+
+	call(quit)
+}
+
+// Closed 窗口 w 已经关闭
+//
+// 由各平台在主线程中调用，最后一个窗口关闭时根据情况调用 OnAllWindowsClosed 注册的函数或是退出消息循环。
+func (l *Lifecycle) Closed(w webview.Desktop) {
+	l.mux.Lock()
+	found := false
+	for i, win := range l.windows {
+		if win == w {
+			l.windows = append(l.windows[:i], l.windows[i+1:]...)
+			found = true
+			break
+		}
+	}
+	remaining := len(l.windows)
+	quitting := l.quitting
+	allClosed := l.allClosed
+	l.mux.Unlock()
+
+	if !found || remaining > 0 {
+		return
+	}
+
+	if quitting || len(allClosed) == 0 {
+		l.exit()
+		return
+	}
+	call(allClosed)
+}
+
+// Quit 关闭所有窗口并退出消息循环
+func (l *Lifecycle) Quit() {
+	l.mux.Lock()
+	l.quitting = true
+	windows := append(make([]webview.Desktop, 0, len(l.windows)), l.windows...)
+	l.mux.Unlock()
+
+	if len(windows) == 0 {
+		l.exit()
+		return
+	}
+
+	// 由最后一个窗口的 Closed 退出消息循环
+	for _, w := range windows {
+		w.Close()
+	}
+}
+
+func call(hooks []func()) {
+	for _, f := range hooks {
+		f()
+	}
+}
@@ -0,0 +1,99 @@
+// SPDX-License-Identifier: MIT
+
+package lifecycle
+
+import (
+	"testing"
+
+	"github.com/issue9/assert/v3"
+
+	"github.com/issue9/webview"
+)
+
+type window struct {
+	webview.Desktop
+	l *Lifecycle
+}
+
+func (w *window) Close() { w.l.Closed(w) }
+
+func newLifecycle() (*Lifecycle, *int) {
+	exits := 0
+	return New(func() { exits++ }), &exits
+}
+
+func TestLifecycle_Run(t *testing.T) {
+	a := assert.New(t, false)
+	l, _ := newLifecycle()
+
+	events := []string{}
+	l.OnReady(func() { events = append(events, "ready1") })
+	l.OnReady(func() { events = append(events, "ready2") })
+	l.OnQuit(func() { events = append(events, "quit") })
+
+	l.Run(func() {
+		events = append(events, "loop")
+		l.Run(func() { events = append(events, "nested") }) // 已经在运行
+	})
+	a.Equal(events, []string{"ready1", "ready2", "loop", "quit"})
+
+	// 可以再次运行
+	events = events[:0]
+	l.Run(func() { events = append(events, "loop") })
+	a.Equal(events, []string{"ready1", "ready2", "loop", "quit"})
+}
+
+func TestLifecycle_Closed(t *testing.T) {
+	a := assert.New(t, false)
+	l, exits := newLifecycle()
+
+	w1, w2 := &window{l: l}, &window{l: l}
+	l.Add(w1)
+	l.Add(w2)
+	a.Equal(l.Windows(), []webview.Desktop{w1, w2})
+
+	w1.Close()
+	a.Equal(l.Windows(), []webview.Desktop{w2}).Equal(*exits, 0)
+
+	// 没有 OnAllWindowsClosed，关闭最后一个窗口时退出。
+	w2.Close()
+	a.Empty(l.Windows()).Equal(*exits, 1)
+
+	// 重复关闭
+	w2.Close()
+	a.Equal(*exits, 1)
+
+	// 有 OnAllWindowsClosed
+	closed := 0
+	l.OnAllWindowsClosed(func() { closed++ })
+	w3 := &window{l: l}
+	l.Add(w3)
+	w3.Close()
+	a.Equal(closed, 1).Equal(*exits, 1)
+}
+
+func TestLifecycle_Quit(t *testing.T) {
+	a := assert.New(t, false)
+	l, exits := newLifecycle()
+
+	closed := 0
+	l.OnAllWindowsClosed(func() { closed++ })
+
+	// 没有窗口
+	l.Quit()
+	a.Equal(*exits, 1)
+
+	l.Add(&window{l: l})
+	l.Add(&window{l: l})
+	l.Quit()
+	a.Empty(l.Windows()).
+		Equal(*exits, 2).
+		Equal(closed, 0)
+
+	// Run 结束之后恢复 quitting 状态
+	l.Run(func() {})
+	w := &window{l: l}
+	l.Add(w)
+	w.Close()
+	a.Equal(*exits, 2).Equal(closed, 1)
+}
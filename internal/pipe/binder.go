@@ -3,26 +3,291 @@
 package pipe
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
 	"reflect"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/issue9/webview"
+	"github.com/issue9/webview/internal/dts"
 )
 
+// 前端发送的消息
+//
+// 消息本身采用 JSON 编码，Params 中的每一项以及 Payload 为 Codec 编码之后的数据：
+// 如果 Codec 实现了 webview.JSONCodec，直接嵌入 JSON 之中，否则为字符串。
 type rpcMessage struct {
+	Type   string            `json:"type,omitempty"`
 	ID     int               `json:"id"`
 	Method string            `json:"method"`
 	Params []json.RawMessage `json:"params"`
+
+	// 前端指定的超时时间，单位为毫秒，为 0 表示采用默认值。
+	Timeout int `json:"timeout,omitempty"`
+
+	// 发送消息的页面的 location.origin 和 location.href
+	Origin string `json:"origin,omitempty"`
+	URL    string `json:"url,omitempty"`
+
+	// 注入的运行时代码持有的令牌，用于确认消息来自顶层页面中的运行时。
+	Token string `json:"token,omitempty"`
+
+	// 以下仅在 Type 为 typeEvent 时有效
+	Event   string          `json:"event,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// rpcMessage.Type 的可选值
+const (
+	typeCall   = ""       // 调用 Go 方法
+	typeCancel = "cancel" // 前端取消了 ID 指定的调用
+	typeLoad   = "load"   // 加载了新的页面
+	typeEvent  = "event"  // 前端发送的事件，不需要返回值。
+)
+
+// 注入前端的运行时代码
+//
+// window._rpc.stub 生成绑定方法在前端的函数，该函数带有 withOptions 方法，可用于指定 AbortSignal 和超时时间；
+// window._rpc.call 向后端发起调用并返回 Promise，返回的 Promise 带有 cancel 方法，可用于取消调用；
+// window._rpc.stream 向后端发起调用并返回流对象，该对象实现了异步迭代器，也可以通过 each 以回调的方式读取；
+// window._rpc.settle 由后端调用，用于完成 call 返回的 Promise 或是结束 stream 返回的流；
+// window._rpc.push 由后端调用，用于向 stream 返回的流推送数据；
+// window._rpc.callback 由后端调用，用于执行作为参数传递给后端的回调函数；
+// window._rpc.keep 和 window._rpc.release 由后端调用，分别用于保留和释放回调函数，未保留的回调函数在调用结束时释放；
+// window._rpc.bytes 由后端调用，将 base64 编码的数据转换为 Uint8Array，window._rpc.base64 则相反；
+// window._rpc.decode 由后端调用，解码由 Codec 编码的数据；
+// window._rpc.emit 由后端调用，用于触发由 window.webview.on 等方法订阅的事件；
+// window.webview.emit 向后端发送事件，由 Binder.On 订阅的函数处理。
+const runtimeJS = `(function() {
+	if (window._rpc) { return; }
+	var RPC = window._rpc = {nextSeq: 1, calls: {}, nextCallback: 1, callbacks: {}};
+	var listeners = {};
+	var WV = window.webview = window.webview || {};
+	WV.on = function(event, fn) {
+		(listeners[event] = listeners[event] || []).push({fn: fn, once: false});
+	};
+	WV.once = function(event, fn) {
+		(listeners[event] = listeners[event] || []).push({fn: fn, once: true});
+	};
+	WV.off = function(event, fn) {
+		if (!fn) {
+			delete listeners[event];
+			return;
+		}
+		var ls = listeners[event] || [];
+		listeners[event] = ls.filter(function(l) { return l.fn !== fn; });
+	};
+	WV.emit = function(event, payload) {
+		window.external.invoke(message({type: "event", event: event, origin: location.origin}, "payload", codec.encode(payload)));
+	};
+	RPC.emit = function(event, payload) {
+		var ls = listeners[event];
+		if (!ls) { return; }
+		listeners[event] = ls.filter(function(l) { return !l.once; });
+		ls.forEach(function(l) { l.fn(payload); });
+	};
+	RPC.base64 = function(v) {
+		var bytes = v instanceof ArrayBuffer ? new Uint8Array(v) : new Uint8Array(v.buffer, v.byteOffset, v.byteLength);
+		var s = "";
+		for (var i = 0; i < bytes.length; i += 0x8000) {
+			s += String.fromCharCode.apply(null, bytes.subarray(i, i + 0x8000));
+		}
+		return btoa(s);
+	};
+	RPC.bytes = function(b64) {
+		var s = atob(b64), bytes = new Uint8Array(s.length);
+		for (var i = 0; i < s.length; i++) { bytes[i] = s.charCodeAt(i); }
+		return bytes;
+	};
+	var codec = {{codec}};
+	var raw = {{raw}}; // codec.encode 的结果为 JSON，可以直接嵌入消息。
+	var token = "{{token}}"; // 仅注入顶层页面，用于证明消息由运行时发送。
+	var message = function(msg, key, data) {
+		msg.token = token;
+		if (key === undefined) { return JSON.stringify(msg); }
+		if (!raw) {
+			msg[key] = data;
+			return JSON.stringify(msg);
+		}
+		var s = JSON.stringify(msg);
+		return s.slice(0, -1) + "," + JSON.stringify(key) + ":" + (Array.isArray(data) ? "[" + data.join(",") + "]" : data) + "}";
+	};
+	RPC.decode = function(data) { return codec.decode(data); };
+	var owned = {}; // 各调用传递的回调函数的 ID
+	var encode = function(seq, params) { // 函数无法被编码，以回调的 ID 代替。
+		return params.map(function(p) {
+			if (typeof p === "function") {
+				var id = RPC.nextCallback++;
+				RPC.callbacks[id] = {fn: p, seq: seq};
+				(owned[seq] = owned[seq] || []).push(id);
+				p = {"$callback": id};
+			}
+			return codec.encode(p);
+		});
+	};
+	RPC.callback = function(id, args) {
+		var cb = RPC.callbacks[id];
+		if (cb) { cb.fn.apply(null, args); }
+	};
+	RPC.keep = function(id) {
+		var cb = RPC.callbacks[id];
+		if (cb) { cb.seq = 0; }
+	};
+	RPC.release = function(id) { delete RPC.callbacks[id]; };
+	RPC.cancel = function(seq) {
+		var c = RPC.calls[seq];
+		if (!c) { return; }
+		delete RPC.calls[seq];
+		window.external.invoke(message({type: "cancel", id: seq}));
+		c.reject({message: "context canceled", code: "canceled"});
+	};
+	var send = function(seq, method, params, options) {
+		options = options || {};
+		var msg = {id: seq, method: method, origin: location.origin, url: location.href};
+		if (options.timeout > 0) { msg.timeout = Math.ceil(options.timeout); }
+		window.external.invoke(message(msg, "params", encode(seq, params)));
+
+		var signal = options.signal;
+		if (!signal) { return; }
+		if (signal.aborted) {
+			RPC.cancel(seq);
+		} else {
+			signal.addEventListener("abort", function() { RPC.cancel(seq); }, {once: true});
+		}
+	};
+	RPC.stub = function(method, stream) {
+		var invoke = function(params, options) {
+			return (stream ? RPC.stream : RPC.call)(method, params, options);
+		};
+		var f = function() { return invoke(Array.prototype.slice.call(arguments)); };
+		f.withOptions = function(options) {
+			return function() { return invoke(Array.prototype.slice.call(arguments), options); };
+		};
+		return f;
+	};
+	RPC.call = function(method, params, options) {
+		var seq = RPC.nextSeq++;
+		var promise = new Promise(function(resolve, reject) {
+			RPC.calls[seq] = {resolve: resolve, reject: reject};
+		});
+		promise.cancel = function() { RPC.cancel(seq); };
+		send(seq, method, params, options);
+		return promise;
+	};
+	RPC.stream = function(method, params, options) {
+		var seq = RPC.nextSeq++;
+		var items = [], waiters = [], result = null;
+		var flush = function() {
+			while (waiters.length > 0 && (items.length > 0 || result)) {
+				var w = waiters.shift();
+				if (items.length > 0) {
+					w.resolve({value: items.shift(), done: false});
+				} else if (result.ok) {
+					w.resolve({value: undefined, done: true});
+				} else {
+					w.reject(result.value);
+				}
+			}
+		};
+		RPC.calls[seq] = {
+			push: function(value) { items.push(value); flush(); },
+			resolve: function() { result = {ok: true}; flush(); },
+			reject: function(err) { result = {ok: false, value: err}; flush(); },
+		};
+		var s = {
+			next: function() {
+				return new Promise(function(resolve, reject) {
+					waiters.push({resolve: resolve, reject: reject});
+					flush();
+				});
+			},
+			return: function() {
+				RPC.cancel(seq);
+				return Promise.resolve({value: undefined, done: true});
+			},
+			cancel: function() { RPC.cancel(seq); },
+			each: function(fn) {
+				return new Promise(function(resolve, reject) {
+					var loop = function() {
+						s.next().then(function(r) {
+							if (r.done) { return resolve(); }
+							fn(r.value);
+							loop();
+						}, reject);
+					};
+					loop();
+				});
+			},
+		};
+		s[Symbol.asyncIterator] = function() { return s; };
+		send(seq, method, params, options);
+		return s;
+	};
+	RPC.settle = function(seq, ok, value) {
+		(owned[seq] || []).forEach(function(id) { // 释放未被保留的回调函数
+			var cb = RPC.callbacks[id];
+			if (cb && cb.seq === seq) { delete RPC.callbacks[id]; }
+		});
+		delete owned[seq];
+		var c = RPC.calls[seq];
+		if (!c) { return; }
+		delete RPC.calls[seq];
+		ok ? c.resolve(value) : c.reject(value);
+	};
+	RPC.push = function(seq, value) {
+		var c = RPC.calls[seq];
+		if (c && c.push) { c.push(value); }
+	};
+	window.external.invoke(message({type: "load"}));
+})()`
+
+// 注入前端的方法
+type stub struct {
+	define string // 定义方法的代码
+	remove string // 删除方法的代码
 }
 
+// 最后一个窗口的 ID，每个窗口对应一个 Binder。
+var windowID int32
+
 type Binder struct {
-	bindings *sync.Map
-	errlog   *log.Logger
-	app      webview.App
+	id          int // 所属窗口的 ID
+	codec       webview.Codec
+	unmarshal   func([]byte, interface{}) error // 解码绑定方法的参数
+	raw         bool                            // Codec 的编码结果是否为 JSON
+	token       string                          // 运行时代码持有的令牌
+	middlewares []webview.Middleware
+	rePanic     bool
+	timeout     time.Duration
+	origins     []string
+	bindings    *sync.Map
+	stubsM      *sync.Mutex
+	stubs       map[string]*stub    // 已经注入了前端代码的方法
+	objects     map[string][]string // 由 BindObject 绑定的命名空间及其包含的方法
+	errlog      *log.Logger
+	app         webview.App
+	pool        *pool
+	notFound    func(string)
+	inject      func(string)
+
+	eventsM *sync.RWMutex
+	events  map[string][]func(json.RawMessage)
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	callsM *sync.Mutex
+	calls  map[int]context.CancelFunc // 当前页面中正在执行的调用
+	page   int                        // 页面的加载次数，用于丢弃已离开页面的调用结果
 
 	eval         func(string)
 	dispatch     func()
@@ -34,22 +299,115 @@ type Binder struct {
 //
 // 在客户端调用通过 Bind 绑定的方法时，计算结果是通过 eval 给到前端，该行为在主线程上异步进行，
 // dispatch 负责触发该行为，eval 执行具体操作。
-func NewBinder(app webview.App, eval func(string), dispatch func(), errlog *log.Logger) *Binder {
-	return &Binder{
-		bindings: &sync.Map{},
-		errlog:   errlog,
-		app:      app,
+//
+// NewBinder 会通过 app.OnLoad 注入前端的运行时代码，调用时 app 应该已经可以正常执行 OnLoad。
+func NewBinder(app webview.App, eval func(string), dispatch func(), o *Options) *Binder {
+	o = sanitizeOptions(o)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	b := &Binder{
+		id:          int(atomic.AddInt32(&windowID, 1)),
+		token:       newToken(),
+		codec:       o.Codec,
+		unmarshal:   o.Codec.Unmarshal,
+		middlewares: o.Middlewares,
+		rePanic:     o.RePanic,
+		timeout:     o.Timeout,
+		origins:     normalizeOrigins(o.Origins),
+		bindings:    &sync.Map{},
+		stubsM:      &sync.Mutex{},
+		stubs:       make(map[string]*stub, 10),
+		objects:     make(map[string][]string, 10),
+		errlog:      o.Error,
+		app:         app,
+		pool:        newPool(o.Workers, o.MethodWorkers, o.Queue),
+		notFound:    o.MethodNotFound,
+		inject:      o.Inject,
+
+		eventsM: &sync.RWMutex{},
+		events:  make(map[string][]func(json.RawMessage), 10),
+
+		ctx:    ctx,
+		cancel: cancel,
+		callsM: &sync.Mutex{},
+		calls:  make(map[int]context.CancelFunc, 10),
 
 		eval:         eval,
 		dispatch:     dispatch,
 		dispatchersM: &sync.Mutex{},
 		dispatchers:  make([]func(), 0, 10),
 	}
+
+	if o.Strict {
+		b.unmarshal = o.Codec.(webview.StrictCodec).UnmarshalStrict
+	}
+	if c, ok := o.Codec.(webview.JSONCodec); ok && c.IsJSON() {
+		b.raw = true
+	}
+	app.OnLoad(strings.NewReplacer(
+		"{{codec}}", o.Codec.JS(),
+		"{{raw}}", strconv.FormatBool(b.raw),
+		"{{token}}", b.token,
+	).Replace(runtimeJS))
+
+	return b
+}
+
+// 生成运行时代码持有的令牌
+func newToken() string {
+	bs := make([]byte, 16)
+	if _, err := rand.Read(bs); err != nil {
+		panic(err) // crypto/rand 出错表示系统无法提供随机数
+	}
+	return fmt.Sprintf("%x", bs)
 }
 
+// WindowID 所属窗口的 ID
+//
+// 即 webview.CallInfo.WindowID，每个 Binder 都不相同。
+func (b *Binder) WindowID() int { return b.id }
+
 // Bind 将 f 以 name 名称绑定在 webview 上
+//
+// f 的第一个参数可以是 [context.Context]，该值由 Binder 提供，
+// 在 Binder 关闭、页面跳转或是前端取消调用时被取消。
+//
+// 如果 f 返回 <-chan T 或是包含 [webview.Stream] 类型的参数（在 context.Context 之后），
+// 那么前端得到的是一个流对象而不是 Promise，具体可参考 [webview.Stream]。
+//
+// f 也可以是 [webview.TimeoutFunc] 或 [webview.OriginFunc]，
+// 分别用于指定该方法的默认超时时间和允许调用的来源。
+//
+// 如果 name 已经被 BindObject 用作命名空间，返回 [webview.ErrNameConflict]。
 func (b *Binder) Bind(name string, f interface{}) error {
-	v := reflect.ValueOf(f)
+	bd := newBinding(f)
+
+	v := reflect.ValueOf(bd.f)
+	if err := checkFunc(v); err != nil {
+		return err
+	}
+
+	b.stubsM.Lock()
+	_, isObject := b.objects[name]
+	b.stubsM.Unlock()
+	if isObject {
+		return fmt.Errorf("%w: %s", webview.ErrNameConflict(), name)
+	}
+
+	b.bind(name, bd, &stub{
+		define: "window[" + jsString(name) + "] = " + invokeJS(name, v.Type()),
+		remove: "delete window[" + jsString(name) + "]",
+	})
+	return nil
+}
+
+// 生成前端调用 name 的函数
+func invokeJS(name string, t reflect.Type) string {
+	return "window._rpc.stub(" + jsString(name) + ", " + strconv.FormatBool(isStream(t)) + ")"
+}
+
+// 检测 v 是否可以被绑定
+func checkFunc(v reflect.Value) error {
 	if v.Kind() != reflect.Func {
 		return webview.ErrOnlyFuncCanBound()
 	}
@@ -61,55 +419,234 @@ func (b *Binder) Bind(name string, f interface{}) error {
 		return webview.ErrBindFuncReturnInvalid()
 	}
 
-	b.bindings.Store(name, f)
-
-	b.app.OnLoad("(function() { var name = " + jsString(name) + ";" + `
-		var RPC = window._rpc = (window._rpc || {nextSeq: 1});
-		window[name] = function() {
-		  var seq = RPC.nextSeq++;
-		  var promise = new Promise(function(resolve, reject) {
-			RPC[seq] = {
-			  resolve: resolve,
-			  reject: reject,
-			};
-		  });
-		  window.external.invoke(JSON.stringify({
-			id: seq,
-			method: name,
-			params: Array.prototype.slice.call(arguments),
-		  }));
-		  return promise;
+	in, _ := dts.InjectedParams(t)
+	for i := 0; i < t.NumIn(); i++ {
+		switch typ := t.In(i); {
+		case i >= in && (typ == callInfoType || typ == contextType || typ == streamType):
+			// 不在开头的参数由前端传递，前端可以借此伪造 *webview.CallInfo 等。
+			return fmt.Errorf("%w: %s can not be parameter %d", webview.ErrBindFuncParamInvalid(), typ, i)
+		case i >= in:
+			if err := checkRules(typ); err != nil {
+				return err
+			}
 		}
-	})()`)
+	}
 
 	return nil
 }
 
-// 调用指定名称的方法
-func (b *Binder) call(name string, params ...json.RawMessage) (interface{}, error) {
-	f, ok := b.bindings.Load(name)
+// 返回 t 从第 in 个参数开始前端至少需要传递的参数数量
+//
+// 末尾的指针类型参数可以省略，省略的参数为 nil，可变参数之前的指针参数也同样适用。
+func requiredParams(t reflect.Type, in int) int {
+	n := t.NumIn()
+	if t.IsVariadic() {
+		n--
+	}
+	for n > in && t.In(n-1).Kind() == reflect.Ptr {
+		n--
+	}
+	return n - in
+}
+
+func argumentsError(method string, required, numIn int, variadic bool, got int) error {
+	var expected string
+	switch {
+	case variadic:
+		expected = "at least " + strconv.Itoa(required)
+	case required == numIn:
+		expected = strconv.Itoa(required)
+	default:
+		expected = strconv.Itoa(required) + " to " + strconv.Itoa(numIn)
+	}
+	return fmt.Errorf("function arguments mismatch: %s expects %s arguments, got %d", method, expected, got)
+}
+
+func (b *Binder) bind(name string, bd *binding, s *stub) {
+	b.bindings.Store(name, bd)
+
+	b.stubsM.Lock()
+	old, injected := b.stubs[name]
+	b.stubs[name] = s
+	b.stubsM.Unlock()
+
+	switch {
+	case b.inject != nil:
+		b.injectStubs()
+	case !injected:
+		b.app.OnLoad(s.define)
+	case old.define != s.define:
+		// 重新绑定，如果 OnLoad 中的代码与新的定义不同（比如是否为流发生了变化），
+		// 需要再次注入，后注入的代码会覆盖之前的定义。
+		b.app.OnLoad(s.define)
+	}
+
+	if injected { // 重新绑定，只需在当前页面中重新定义。
+		b.post(func() { b.eval(s.define) })
+	}
+}
+
+// Unbind 解除 name 的绑定
+//
+// 同时会删除当前页面中的 window[name]。
+// 如果 name 是由 BindObject 绑定的命名空间，则解除该命名空间下所有方法的绑定。
+//
+// NOTE: 如果平台未提供 Options.Inject，已经通过 OnLoad 注入的代码无法删除，之后加载的页面依然会注入 window[name]，
+// 直到页面向 Binder 报告加载完成之后才会被删除，在此期间的调用会返回 webview.ErrMethodNotFound。
+func (b *Binder) Unbind(name string) {
+	b.stubsM.Lock()
+	names, isObject := b.objects[name]
+	delete(b.objects, name)
+	b.stubsM.Unlock()
+
+	if !isObject {
+		names = []string{name}
+	}
+	for _, n := range names {
+		b.unbind(n)
+	}
+}
+
+func (b *Binder) unbind(name string) {
+	if _, found := b.bindings.LoadAndDelete(name); !found {
+		return
+	}
+
+	b.stubsM.Lock()
+	s := b.stubs[name]
+	b.stubsM.Unlock()
+	b.post(func() { b.eval(s.remove) })
+
+	if b.inject != nil {
+		b.injectStubs()
+	}
+}
+
+// 通过 Options.Inject 注入所有仍然有效的方法
+func (b *Binder) injectStubs() {
+	b.stubsM.Lock()
+	names := make([]string, 0, len(b.stubs))
+	for name := range b.stubs {
+		if _, found := b.bindings.Load(name); found {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names) // 保证每次生成的代码相同
+
+	defines := make([]string, 0, len(names))
+	for _, name := range names {
+		defines = append(defines, b.stubs[name].define)
+	}
+	b.stubsM.Unlock()
+
+	b.inject(strings.Join(defines, ";\n"))
+}
+
+// 删除新页面中已经解除绑定的方法
+func (b *Binder) removeUnbound() {
+	var js string
+	b.stubsM.Lock()
+	for name, s := range b.stubs {
+		if _, found := b.bindings.Load(name); !found {
+			js += s.remove + ";"
+		}
+	}
+	b.stubsM.Unlock()
+
+	if js != "" {
+		b.post(func() { b.eval(js) })
+	}
+}
+
+// 经由中间件调用 req 指定的方法
+//
+// 绑定的方法和中间件中的 panic 都会被转换为 webview.ErrInternal，
+// 绑定方法中的 panic 在中间件看来也只是普通的错误。
+func (b *Binder) invoke(req *request) (ret interface{}, err error) {
+	defer b.recover(req.method, &err)
+
+	h := func(inv *webview.Invocation) (ret interface{}, err error) {
+		defer b.recoverNested(req.method, &err)
+		req.ctx, req.params = inv.Context, inv.Params
+		return b.call(req)
+	}
+	for i := len(b.middlewares) - 1; i >= 0; i-- {
+		h = b.middlewares[i](h)
+	}
+
+	return h(&webview.Invocation{Context: req.ctx, Method: req.method, Params: req.params, Caller: req.info})
+}
+
+// 调用 req 指定的方法
+func (b *Binder) call(req *request) (interface{}, error) {
+	bd, ok := b.bindings.Load(req.method)
 	if !ok {
-		return nil, nil
+		b.notFound(req.method)
+		return nil, fmt.Errorf("%w: %s", webview.ErrMethodNotFound(), req.method)
 	}
 
-	v := reflect.ValueOf(f)
+	origins := bd.(*binding).origins
+	if origins == nil {
+		origins = b.origins
+	}
+	if !permitted(origins, req.pageOrigin, req.info.Origin) {
+		return nil, fmt.Errorf("%w: %s", webview.ErrOriginNotAllowed(), req.method)
+	}
+
+	v := reflect.ValueOf(bd.(*binding).f)
+	in, _ := dts.InjectedParams(v.Type()) // 需要从前端获取的第一个参数的索引
+	args := make([]reflect.Value, 0, v.Type().NumIn())
+	for i := 0; i < in; i++ {
+		switch v.Type().In(i) {
+		case callInfoType:
+			args = append(args, reflect.ValueOf(req.info))
+		case contextType:
+			args = append(args, reflect.ValueOf(req.ctx))
+		default: // streamType
+			args = append(args, reflect.ValueOf(&stream{b: b, req: req}))
+		}
+	}
+
+	params := req.params
 	isVariadic := v.Type().IsVariadic()
-	numIn := v.Type().NumIn()
-	if (isVariadic && len(params) < numIn-1) || (!isVariadic && len(params) != numIn) {
-		return nil, errors.New("function arguments mismatch")
+	numIn := v.Type().NumIn() - in
+	required := requiredParams(v.Type(), in)
+	if len(params) < required || (!isVariadic && len(params) > numIn) {
+		return nil, argumentsError(req.method, required, numIn, isVariadic, len(params))
 	}
-	args := []reflect.Value{}
+	var invalid []*webview.FieldError
 	for i := range params {
-		var arg reflect.Value
+		var typ reflect.Type
 		if isVariadic && i >= numIn-1 {
-			arg = reflect.New(v.Type().In(numIn - 1).Elem())
+			typ = v.Type().In(in + numIn - 1).Elem()
 		} else {
-			arg = reflect.New(v.Type().In(i))
+			typ = v.Type().In(in + i)
+		}
+
+		if typ == callbackType {
+			cb, err := b.newCallback(req, params[i])
+			if err != nil {
+				return nil, decodeError(i, err)
+			}
+			args = append(args, reflect.ValueOf(cb))
+			continue
 		}
-		if err := json.Unmarshal(params[i], arg.Interface()); err != nil {
-			return nil, err
+
+		arg := reflect.New(typ)
+		if err := b.unmarshal([]byte(params[i]), arg.Interface()); err != nil {
+			return nil, decodeError(i, err)
 		}
 		args = append(args, arg.Elem())
+		invalid = append(invalid, validate(i, arg.Elem())...)
+	}
+	if len(invalid) > 0 {
+		return nil, &webview.ValidationError{Fields: invalid}
+	}
+	if isVariadic {
+		numIn--
+	}
+	for i := len(params); i < numIn; i++ { // 省略的参数
+		args = append(args, reflect.Zero(v.Type().In(in+i)))
 	}
 
 	res := v.Call(args)
@@ -140,27 +677,334 @@ func (b *Binder) call(name string, params ...json.RawMessage) (interface{}, erro
 }
 
 // MessageHandler 处理前端的调用请求
-func (b *Binder) MessageHandler(msg string) {
+//
+// 相当于 HandleMessage(msg, Source{})，即消息的来源完全由前端报告。
+func (b *Binder) MessageHandler(msg string) { b.HandleMessage(msg, Source{}) }
+
+// HandleMessage 处理来自 src 的前端调用请求
+//
+// 绑定的方法在新的 goroutine 中执行，不会阻塞调用 HandleMessage 的线程，
+// 同时执行的数量受 Options.Workers 和 Options.MethodWorkers 的限制，
+// 超出限制的调用会等待，直到有空闲的位置或是调用被取消，Options.Middlewares 在此之后执行。
+// 等待的调用超过 Options.Queue 时，新的调用直接以 webview.ErrBusy 拒绝，不会启动 goroutine。
+// 只有最终结果的 eval 会通过 dispatch 回到主线程执行。
+//
+// src 由平台提供，优先于前端报告的来源，调用的来源不被允许时以 webview.ErrOriginNotAllowed 拒绝。
+// 如果平台提供了 src.URL 但无法提供 src.Origin，那么只接受由顶层页面中的运行时发送的消息，
+// 其来源即为顶层页面，其它框架发送的消息会被丢弃。
+// 页面加载和取消调用的消息在所有平台上都只接受由顶层页面中的运行时发送的。
+func (b *Binder) HandleMessage(msg string, src Source) {
 	rpc := rpcMessage{}
 	if err := json.Unmarshal([]byte(msg), &rpc); err != nil {
 		b.errlog.Printf("invalid RPC message %v", err)
 		return
 	}
 
-	id := strconv.Itoa(rpc.ID)
-	var f func()
-	if res, err := b.call(rpc.Method, rpc.Params...); err != nil {
-		f = func() {
-			b.eval("window._rpc[" + id + "].reject(" + jsString(err.Error()) + "); window._rpc[" + id + "] = undefined")
+	reported := normalizeOrigin(rpc.Origin) // 由前端报告的 origin，可能被页面中的代码伪造。
+	pageOrigin := reported
+	if src.URL != "" {
+		pageOrigin = urlOrigin(src.URL)
+	}
+	origin := reported
+	switch {
+	case src.Origin != "":
+		origin = normalizeOrigin(src.Origin)
+	case src.URL != "":
+		// 无法获知发送消息的框架，其它框架可以直接向平台发送消息并伪造 origin，
+		// 只有顶层页面中的运行时持有令牌。
+		if !b.validToken(&rpc) {
+			b.errlog.Printf("untrusted RPC message from %s", reported)
+			return
+		}
+		origin = pageOrigin
+	case origin == "":
+		origin = pageOrigin
+	}
+	url := rpc.URL
+	if src.URL != "" {
+		url = src.URL
+	}
+
+	switch rpc.Type {
+	case typeCall:
+		params := make([]string, 0, len(rpc.Params))
+		for _, p := range rpc.Params {
+			data, err := b.codecData(p)
+			if err != nil {
+				b.errlog.Printf("invalid RPC message %v", err)
+				return
+			}
+			params = append(params, data)
+		}
+
+		timeout := time.Duration(rpc.Timeout) * time.Millisecond
+		if timeout <= 0 {
+			timeout = b.defaultTimeout(rpc.Method)
+		}
+		ctx, page := b.begin(rpc.ID, timeout)
+		req := &request{
+			ctx:        ctx,
+			page:       page,
+			id:         rpc.ID,
+			method:     rpc.Method,
+			params:     params,
+			pageOrigin: pageOrigin,
+			info:       &webview.CallInfo{WindowID: b.id, URL: url, Origin: origin, ID: rpc.ID},
+		}
+		if !b.pool.enter() {
+			b.settle(req, false, b.marshalError(fmt.Errorf("%w: %s", webview.ErrBusy(), req.method)))
+			b.end(req.id, req.page)
+			return
+		}
+		go b.handleCall(req)
+	case typeCancel:
+		if !b.fromRuntime(&rpc, pageOrigin, origin) {
+			b.errlog.Printf("cancel from %s not allowed", origin)
+			return
+		}
+		b.end(rpc.ID, b.currentPage())
+	case typeLoad:
+		if !b.fromRuntime(&rpc, pageOrigin, origin) {
+			b.errlog.Printf("load from %s not allowed", origin)
+			return
+		}
+		b.navigate()
+		b.removeUnbound()
+	case typeEvent:
+		if !permitted(b.origins, pageOrigin, origin) {
+			b.errlog.Printf("event %s from %s not allowed", rpc.Event, origin)
+			return
+		}
+		payload, err := b.codecData(rpc.Payload)
+		if err != nil {
+			b.errlog.Printf("invalid payload of event %s: %v", rpc.Event, err)
+			return
 		}
-	} else if data, err := json.Marshal(res); err != nil {
-		f = func() {
-			b.eval("window._rpc[" + id + "].reject(" + jsString(err.Error()) + "); window._rpc[" + id + "] = undefined")
+		if !b.pool.enter() {
+			b.errlog.Printf("event %s dropped: %v", rpc.Event, webview.ErrBusy())
+			return
 		}
+		go b.handleEvent(rpc.Event, payload)
+	default:
+		b.errlog.Printf("invalid RPC message type %s", rpc.Type)
+	}
+}
+
+func (b *Binder) validToken(rpc *rpcMessage) bool {
+	return subtle.ConstantTimeCompare([]byte(rpc.Token), []byte(b.token)) == 1
+}
+
+// 消息是否由顶层页面中的运行时发送
+//
+// load 和 cancel 会取消页面中的调用，只接受顶层页面中的运行时发送的，
+// 否则其它框架可以借此取消所有的调用或是释放所有的回调函数。
+func (b *Binder) fromRuntime(rpc *rpcMessage, pageOrigin, origin string) bool {
+	return b.validToken(rpc) && origin == pageOrigin
+}
+
+// 从消息中获取 Codec 编码的数据
+func (b *Binder) codecData(data json.RawMessage) (string, error) {
+	if b.raw || len(data) == 0 {
+		return string(data), nil
+	}
+
+	var s string
+	err := json.Unmarshal(data, &s)
+	return s, err
+}
+
+// 页面 pageOrigin 中的框架 origin 是否可以调用限制为 origins 的方法
+func permitted(origins []string, pageOrigin, origin string) bool {
+	return len(origins) == 0 || (matchOrigin(origins, pageOrigin) && matchOrigin(origins, origin))
+}
+
+func (b *Binder) handleCall(req *request) {
+	defer b.pool.leave()
+	defer b.end(req.id, req.page)
+
+	if ctx := req.ctx; hasDeadline(ctx) { // 超时之后立即通知前端，不必等待方法返回。
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			select {
+			case <-ctx.Done():
+				if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					b.settle(req, false, b.marshalError(ctx.Err()))
+				}
+			case <-done:
+			}
+		}()
+	}
+
+	release, err := b.pool.acquire(req.ctx, req.method)
+	if err != nil {
+		b.settle(req, false, b.marshalError(err))
+		return
+	}
+	defer release() // 对于流，需要等到流结束才释放。
+
+	res, err := b.invoke(req)
+	if err != nil {
+		b.settle(req, false, b.marshalError(err))
+		return
+	}
+
+	if v := reflect.ValueOf(res); v.Kind() == reflect.Chan && v.Type().ChanDir() == reflect.RecvDir {
+		b.drain(req, v)
+		return
+	}
+
+	if data, err := b.marshal(res); err != nil {
+		b.settle(req, false, b.marshalError(err))
 	} else {
-		f = func() {
-			b.eval("window._rpc[" + id + "].resolve(" + string(data) + "); window._rpc[" + id + "] = undefined")
+		b.settle(req, true, data)
+	}
+}
+
+// 完成前端 req 对应的 Promise 或是流
+//
+// value 为由 marshal 生成的数据，ok 为 false 时，value 应该是由 marshalError 生成的错误对象。
+//
+// 同一调用只有第一次有效，之后的调用会被忽略。
+// 参数中未通过 webview.Callback.Keep 保留的回调函数也随之释放。
+func (b *Binder) settle(req *request, ok bool, value string) {
+	req.callbacksM.Lock()
+	defer req.callbacksM.Unlock()
+
+	if !atomic.CompareAndSwapInt32(&req.settled, 0, 1) {
+		return
+	}
+	releaseCallbacks(req)
+	b.enqueue(req.page, "window._rpc.settle("+strconv.Itoa(req.id)+", "+strconv.FormatBool(ok)+", "+value+")")
+}
+
+func (b *Binder) handleEvent(event string, data string) {
+	defer b.pool.leave()
+
+	b.eventsM.RLock()
+	handlers := b.events[event]
+	b.eventsM.RUnlock()
+	if len(handlers) == 0 {
+		return
+	}
+
+	var payload json.RawMessage
+	if err := b.codec.Unmarshal([]byte(data), &payload); err != nil {
+		b.errlog.Printf("invalid payload of event %s: %v", event, err)
+		return
+	}
+
+	release, err := b.pool.acquire(b.ctx, "event:"+event) // 与绑定的方法共用并发限制
+	if err != nil {
+		return
+	}
+	defer release()
+
+	for _, h := range handlers {
+		func() { // panic 只记录日志，不影响其它订阅者。
+			var err error
+			defer b.recover("event:"+event, &err)
+			h(payload)
+		}()
+	}
+}
+
+// On 订阅前端通过 window.webview.emit 发送的事件
+//
+// 同一事件可以订阅多次，f 在主线程之外执行，payload 为前端传递的数据，
+// 不论采用何种 Codec，payload 都会被转换为 JSON 格式。
+func (b *Binder) On(event string, f func(payload json.RawMessage)) {
+	b.eventsM.Lock()
+	defer b.eventsM.Unlock()
+	b.events[event] = append(b.events[event], f)
+}
+
+// Off 取消 event 事件的所有订阅
+func (b *Binder) Off(event string) {
+	b.eventsM.Lock()
+	defer b.eventsM.Unlock()
+	delete(b.events, event)
+}
+
+func hasDeadline(ctx context.Context) bool {
+	_, ok := ctx.Deadline()
+	return ok
+}
+
+// 绑定方法 name 的默认超时时间
+func (b *Binder) defaultTimeout(name string) time.Duration {
+	if bd, found := b.bindings.Load(name); found && bd.(*binding).timeout > 0 {
+		return bd.(*binding).timeout
+	}
+	return b.timeout
+}
+
+// 登记一次新的调用并返回该调用的 context.Context 和所属的页面
+//
+// timeout 大于 0 时，返回的 context.Context 会在超时之后被取消。
+func (b *Binder) begin(id int, timeout time.Duration) (context.Context, int) {
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(b.ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(b.ctx)
+	}
+
+	b.callsM.Lock()
+	defer b.callsM.Unlock()
+	if c, found := b.calls[id]; found { // 同一页面不应该出现重复的 ID，以防万一。
+		c()
+	}
+	b.calls[id] = cancel
+	return ctx, b.page
+}
+
+// 结束 page 页面中由 id 指定的调用
+func (b *Binder) end(id, page int) {
+	b.callsM.Lock()
+	defer b.callsM.Unlock()
+
+	if page != b.page { // 页面已经跳转，navigate 已经取消了所有的调用。
+		return
+	}
+	if cancel, found := b.calls[id]; found {
+		cancel()
+		delete(b.calls, id)
+	}
+}
+
+// 页面跳转，取消旧页面上所有正在执行的调用。
+func (b *Binder) navigate() {
+	b.callsM.Lock()
+	defer b.callsM.Unlock()
+
+	for _, cancel := range b.calls {
+		cancel()
+	}
+	b.calls = make(map[int]context.CancelFunc, 10)
+	b.page++
+}
+
+func (b *Binder) currentPage() int {
+	b.callsM.Lock()
+	defer b.callsM.Unlock()
+	return b.page
+}
+
+// 将 js 放入主线程执行，如果 page 页面已经离开，则不再执行。
+func (b *Binder) enqueue(page int, js string) {
+	b.post(func() {
+		if b.currentPage() == page {
+			b.eval(js)
 		}
+	})
+}
+
+// 将 f 放入主线程执行
+func (b *Binder) post(f func()) {
+	if b.ctx.Err() != nil { // 已关闭
+		return
 	}
 
 	b.dispatchersM.Lock()
@@ -170,13 +1014,70 @@ func (b *Binder) MessageHandler(msg string) {
 	b.dispatch() // 触发主线程调用 DispatchCallback
 }
 
+// Emit 向前端发送事件
+//
+// payload 经由 Codec 编码之后传递给前端由 window.webview.on 订阅的函数。
+// 该方法可以在任意 goroutine 中调用，事件会在主线程上异步发送给当前页面。
+func (b *Binder) Emit(event string, payload interface{}) error {
+	data, err := b.marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	js := "window._rpc.emit(" + jsString(event) + ", " + data + ")"
+	b.post(func() { b.eval(js) })
+	return nil
+}
+
+// Dispatch 将 f 放入主线程异步执行
+//
+// 可以在任意 goroutine 中调用，Binder 关闭之后 f 不会再被执行。
+func (b *Binder) Dispatch(f func()) { b.post(f) }
+
+// DispatchSync 将 f 放入主线程执行并等待其完成
+//
+// 如果在 f 执行之前 Binder 已经关闭，则返回 webview.ErrClosed。
+//
+// 与 webview.App.DispatchSync 相同，在主线程中调用时应该直接执行 f。
+// Binder 无法判断当前是否处于主线程，由各平台在调用此方法之前判断，
+// 此方法只处理非主线程的调用，在主线程中调用会一直阻塞。
+func (b *Binder) DispatchSync(f func()) error {
+	var state int32 // 0 表示等待执行，1 表示已经开始执行，2 表示已经放弃。
+	done := make(chan struct{})
+	b.post(func() {
+		if atomic.CompareAndSwapInt32(&state, 0, 1) {
+			defer close(done)
+			f()
+		}
+	})
+
+	select {
+	case <-done:
+		return nil
+	case <-b.ctx.Done():
+		if atomic.CompareAndSwapInt32(&state, 0, 2) {
+			return webview.ErrClosed()
+		}
+		<-done // 已经开始执行，等待其完成。
+		return nil
+	}
+}
+
+// DispatchCallback 执行所有通过 dispatch 放入主线程的函数
+//
+// 由各平台在主线程中调用，执行的函数中可以再次调用 Dispatch 等方法。
 func (b *Binder) DispatchCallback() {
 	b.dispatchersM.Lock()
-	defer b.dispatchersM.Unlock()
+	fs := b.dispatchers
+	b.dispatchers = make([]func(), 0, 10)
+	b.dispatchersM.Unlock()
 
-	for _, f := range b.dispatchers {
+	for _, f := range fs {
 		f()
 	}
-
-	b.dispatchers = b.dispatchers[:0] // 清除已执行的函数
 }
+
+// Close 关闭 Binder
+//
+// 所有正在执行的调用的 context.Context 都将被取消，之后的调用结果也不会再传递给前端。
+func (b *Binder) Close() { b.cancel() }
@@ -0,0 +1,847 @@
+// SPDX-License-Identifier: MIT
+
+package pipe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v3"
+
+	"github.com/issue9/webview"
+	"github.com/issue9/webview/codec"
+)
+
+type testApp struct {
+	webview.App
+	scripts []string
+}
+
+func (app *testApp) OnLoad(js string) { app.scripts = append(app.scripts, js) }
+
+// 返回的 chan 接收所有通过 eval 执行的代码
+func newTestBinder(a *assert.Assertion) (*Binder, *testApp, chan string) {
+	app := &testApp{}
+	evals := make(chan string, 10)
+
+	var b *Binder
+	b = NewBinder(app, func(js string) { evals <- js }, func() { b.DispatchCallback() }, &Options{
+		Error:         log.New(os.Stderr, "", 0),
+		Workers:       2,
+		MethodWorkers: 1,
+	})
+	a.NotNil(b).Length(app.scripts, 1).
+		Contains(app.scripts[0], "var raw = true;"). // JSON 的参数直接嵌入消息
+		Contains(app.scripts[0], `var token = "`+b.token+`";`)
+
+	return b, app, evals
+}
+
+// 前端解码 data 的表达式
+func decoded(data string) string { return "window._rpc.decode(" + jsString(data) + ")" }
+
+func waitEval(a *assert.Assertion, evals chan string) string {
+	select {
+	case js := <-evals:
+		return js
+	case <-time.After(time.Second):
+		a.TB().Fatal("等待 eval 超时")
+	}
+	return ""
+}
+
+func TestBinder_Bind(t *testing.T) {
+	a := assert.New(t, false)
+	b, app, evals := newTestBinder(a)
+
+	a.ErrorIs(b.Bind("f", 5), webview.ErrOnlyFuncCanBound())
+	a.ErrorIs(b.Bind("f", func() (int, int) { return 1, 1 }), webview.ErrBindFuncReturnInvalid())
+	a.ErrorIs(b.Bind("f", func() (int, error, int) { return 1, nil, 1 }), webview.ErrBindFuncReturnInvalid())
+	a.ErrorIs(b.Bind("f", func(int, webview.Stream) {}), webview.ErrBindFuncParamInvalid())
+	a.ErrorIs(b.Bind("f", func(int, context.Context) {}), webview.ErrBindFuncParamInvalid())
+	a.ErrorIs(b.Bind("f", func(context.Context, string, *webview.CallInfo) {}), webview.ErrBindFuncParamInvalid())
+	a.ErrorIs(b.Bind("f", func(context.Context, context.Context) {}), webview.ErrBindFuncParamInvalid())
+	a.ErrorIs(b.Bind("f", func(webview.Stream, context.Context) {}), webview.ErrBindFuncParamInvalid())
+
+	a.NotError(b.Bind("add", func(x, y int) int { return x + y }))
+	a.Length(app.scripts, 2)
+
+	b.MessageHandler(`{"id":1,"method":"add","params":[1,2]}`)
+	a.Equal(waitEval(a, evals), `window._rpc.settle(1, true, `+decoded(`3`)+`)`)
+
+	b.MessageHandler(`{"id":2,"method":"add","params":[1]}`)
+	a.Equal(waitEval(a, evals), `window._rpc.settle(2, false, `+decoded(`{"message":"function arguments mismatch: add expects 2 arguments, got 1"}`)+`)`)
+
+	b.MessageHandler(`{"id":3,"method":"not-exists","params":[1]}`)
+	a.Equal(waitEval(a, evals), `window._rpc.settle(3, false, `+decoded(`{"message":"method not found: not-exists","code":"method_not_found"}`)+`)`)
+}
+
+func TestBinder_optional(t *testing.T) {
+	a := assert.New(t, false)
+	b, _, evals := newTestBinder(a)
+
+	a.NotError(b.Bind("opt", func(ctx context.Context, x int, y *int, z *string) string {
+		return fmt.Sprint(x, y == nil, z == nil)
+	}))
+	a.NotError(b.Bind("variadic", func(x *int, y *int, z ...int) string {
+		return fmt.Sprint(x == nil, y == nil, len(z))
+	}))
+
+	b.MessageHandler(`{"id":1,"method":"opt","params":[1]}`)
+	a.Equal(waitEval(a, evals), `window._rpc.settle(1, true, `+decoded(`"1 true true"`)+`)`)
+
+	b.MessageHandler(`{"id":2,"method":"opt","params":[1,2]}`)
+	a.Equal(waitEval(a, evals), `window._rpc.settle(2, true, `+decoded(`"1 false true"`)+`)`)
+
+	b.MessageHandler(`{"id":3,"method":"opt","params":[1,2,"z"]}`)
+	a.Equal(waitEval(a, evals), `window._rpc.settle(3, true, `+decoded(`"1 false false"`)+`)`)
+
+	b.MessageHandler(`{"id":4,"method":"opt","params":[]}`)
+	a.Equal(waitEval(a, evals), `window._rpc.settle(4, false, `+decoded(`{"message":"function arguments mismatch: opt expects 1 to 3 arguments, got 0"}`)+`)`)
+
+	b.MessageHandler(`{"id":5,"method":"opt","params":[1,2,3,4]}`)
+	a.Equal(waitEval(a, evals), `window._rpc.settle(5, false, `+decoded(`{"message":"function arguments mismatch: opt expects 1 to 3 arguments, got 4"}`)+`)`)
+
+	b.MessageHandler(`{"id":6,"method":"variadic","params":[]}`)
+	a.Equal(waitEval(a, evals), `window._rpc.settle(6, true, `+decoded(`"true true 0"`)+`)`)
+
+	b.MessageHandler(`{"id":7,"method":"variadic","params":[1,null,3,4]}`)
+	a.Equal(waitEval(a, evals), `window._rpc.settle(7, true, `+decoded(`"false true 2"`)+`)`)
+
+	a.NotError(b.Bind("required", func(x int, y ...int) {}))
+	b.MessageHandler(`{"id":8,"method":"required","params":[]}`)
+	a.Equal(waitEval(a, evals), `window._rpc.settle(8, false, `+decoded(`{"message":"function arguments mismatch: required expects at least 1 arguments, got 0"}`)+`)`)
+}
+
+func TestBinder_context(t *testing.T) {
+	a := assert.New(t, false)
+	b, _, evals := newTestBinder(a)
+
+	started := make(chan struct{}, 1)
+	a.NotError(b.Bind("wait", func(ctx context.Context, v int) (int, error) {
+		started <- struct{}{}
+		<-ctx.Done()
+		return v, ctx.Err()
+	}))
+
+	// 前端取消
+	b.MessageHandler(`{"id":1,"method":"wait","params":[1]}`)
+	<-started
+	b.MessageHandler(`{"type":"cancel","id":1,"token":"` + b.token + `"}`)
+	a.Equal(waitEval(a, evals), `window._rpc.settle(1, false, `+decoded(`{"message":"context canceled","code":"canceled"}`)+`)`)
+
+	// 页面跳转，旧页面的结果不再传递给前端。
+	b.MessageHandler(`{"id":2,"method":"wait","params":[2]}`)
+	<-started
+	b.MessageHandler(`{"type":"load","token":"` + b.token + `"}`)
+	select {
+	case js := <-evals:
+		a.TB().Fatalf("不应该执行 %s", js)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// 关闭
+	b.MessageHandler(`{"id":1,"method":"wait","params":[3]}`)
+	<-started
+	b.Close()
+	select {
+	case js := <-evals:
+		a.TB().Fatalf("不应该执行 %s", js)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestBinder_forged(t *testing.T) {
+	a := assert.New(t, false)
+	evals := make(chan string, 10)
+	var b *Binder
+	errlog := &bytes.Buffer{}
+	b = NewBinder(&testApp{}, func(js string) { evals <- js }, func() { b.DispatchCallback() }, &Options{
+		Error: log.New(errlog, "", 0),
+	})
+	token := `,"token":"` + b.token + `"`
+
+	started := make(chan struct{}, 1)
+	a.NotError(b.Bind("wait", func(ctx context.Context, v int) (int, error) {
+		started <- struct{}{}
+		<-ctx.Done()
+		return v, ctx.Err()
+	}))
+
+	b.HandleMessage(`{"id":1,"method":"wait","params":[1]`+token+`}`, Source{URL: "https://app.example.com/"})
+	<-started
+
+	// 未持有令牌的框架
+	for _, src := range []Source{{}, {URL: "https://app.example.com/"}, {URL: "https://app.example.com/", Origin: "https://app.example.com"}} {
+		b.HandleMessage(`{"type":"cancel","id":1}`, src)
+		b.HandleMessage(`{"type":"cancel","id":1,"token":"x"}`, src)
+		b.HandleMessage(`{"type":"load"}`, src)
+		b.HandleMessage(`{"type":"load","token":"x"}`, src)
+	}
+
+	// 持有令牌，但平台报告来自其它框架。
+	b.HandleMessage(`{"type":"cancel","id":1`+token+`}`, Source{URL: "https://app.example.com/", Origin: "https://ads.com"})
+	b.HandleMessage(`{"type":"load"`+token+`}`, Source{URL: "https://app.example.com/", Origin: "https://ads.com"})
+
+	select {
+	case js := <-evals:
+		a.TB().Fatalf("不应该执行 %s", js)
+	case <-time.After(100 * time.Millisecond):
+	}
+	a.Equal(strings.Count(errlog.String(), "not allowed"), 10).
+		Equal(strings.Count(errlog.String(), "untrusted RPC message"), 4)
+
+	// 顶层页面中的运行时
+	b.HandleMessage(`{"type":"cancel","id":1`+token+`}`, Source{URL: "https://app.example.com/", Origin: "https://app.example.com"})
+	a.Equal(waitEval(a, evals), `window._rpc.settle(1, false, `+decoded(`{"message":"context canceled","code":"canceled"}`)+`)`)
+}
+
+func TestBinder_busy(t *testing.T) {
+	a := assert.New(t, false)
+	const calls = 1000
+	evals := make(chan string, calls)
+	var b *Binder
+	b = NewBinder(&testApp{}, func(js string) { evals <- js }, func() { b.DispatchCallback() }, &Options{
+		Error:   log.New(os.Stderr, "", 0),
+		Workers: 2,
+		Queue:   3,
+	})
+
+	release := make(chan struct{})
+	a.NotError(b.Bind("block", func() int {
+		<-release
+		return 1
+	}))
+
+	goroutines := runtime.NumGoroutine()
+	for i := 1; i <= calls; i++ {
+		b.MessageHandler(`{"id":` + strconv.Itoa(i) + `,"method":"block","params":[]}`)
+	}
+	a.True(runtime.NumGoroutine()-goroutines <= 5) // Workers + Queue
+
+	// 超出的调用立即被拒绝
+	busy := decoded(`{"message":"too many pending calls: block","code":"busy"}`)
+	for i := 6; i <= calls; i++ {
+		a.Equal(waitEval(a, evals), `window._rpc.settle(`+strconv.Itoa(i)+`, false, `+busy+`)`)
+	}
+
+	close(release)
+	for i := 0; i < 5; i++ {
+		a.Contains(waitEval(a, evals), `, true, `+decoded(`1`)+`)`)
+	}
+
+	// 位置释放之后可以继续调用
+	b.MessageHandler(`{"id":1001,"method":"block","params":[]}`)
+	a.Equal(waitEval(a, evals), `window._rpc.settle(1001, true, `+decoded(`1`)+`)`)
+}
+
+func TestBinder_timeout(t *testing.T) {
+	a := assert.New(t, false)
+	b, _, evals := newTestBinder(a)
+	timeout := `{"message":"context deadline exceeded","code":"timeout"}`
+
+	// 方法忽略了 ctx，依然在超时之后拒绝。
+	release := make(chan struct{})
+	a.NotError(b.Bind("block", webview.WithTimeout(func(ctx context.Context) int {
+		<-release
+		return 1
+	}, 50*time.Millisecond)))
+	b.MessageHandler(`{"id":1,"method":"block","params":[]}`)
+	a.Equal(waitEval(a, evals), `window._rpc.settle(1, false, `+decoded(timeout)+`)`)
+	close(release)
+	select { // 方法返回之后不会再次传递结果
+	case js := <-evals:
+		a.TB().Fatalf("不应该执行 %s", js)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// 前端指定的超时时间优先
+	a.NotError(b.Bind("wait", webview.WithTimeout(func(ctx context.Context) (time.Duration, error) {
+		deadline, _ := ctx.Deadline()
+		<-ctx.Done()
+		return time.Until(deadline), ctx.Err()
+	}, time.Hour)))
+	start := time.Now()
+	b.MessageHandler(`{"id":2,"method":"wait","params":[],"timeout":30}`)
+	a.Equal(waitEval(a, evals), `window._rpc.settle(2, false, `+decoded(timeout)+`)`).
+		True(time.Since(start) < time.Second)
+
+	// Options.Timeout
+	b.timeout = 30 * time.Millisecond
+	a.NotError(b.Bind("ctx", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}))
+	b.MessageHandler(`{"id":3,"method":"ctx","params":[]}`)
+	a.Equal(waitEval(a, evals), `window._rpc.settle(3, false, `+decoded(timeout)+`)`)
+
+	a.ErrorIs(b.Bind("f", webview.WithTimeout(5, time.Second)), webview.ErrOnlyFuncCanBound())
+}
+
+func TestBinder_validate(t *testing.T) {
+	a := assert.New(t, false)
+	b, _, evals := newTestBinder(a)
+
+	a.Error(b.Bind("invalid", func(v struct {
+		V bool `validate:"max=5"`
+	}) {
+	}))
+
+	called := false
+	a.NotError(b.Bind("save", func(v *address, p positive) { called = true }))
+
+	b.MessageHandler(`{"id":1,"method":"save","params":[{"zip":"1234567"},0]}`)
+	a.Equal(waitEval(a, evals), `window._rpc.settle(1, false, `+decoded(`{"message":"invalid params: arg0.city is required; arg0.zip length must be 6; arg1 must be positive","code":"invalid_params","data":[{"param":0,"field":"city","rule":"required","message":"is required"},{"param":0,"field":"zip","rule":"len","message":"length must be 6"},{"param":1,"rule":"validate","message":"must be positive"}]}`)+`)`)
+	a.False(called)
+
+	b.MessageHandler(`{"id":2,"method":"save","params":[{"city":"c"},1]}`)
+	a.Equal(waitEval(a, evals), `window._rpc.settle(2, true, `+decoded(`null`)+`)`)
+	a.True(called)
+}
+
+func TestBinder_strict(t *testing.T) {
+	a := assert.New(t, false)
+	evals := make(chan string, 10)
+	var b *Binder
+	b = NewBinder(&testApp{}, func(js string) { evals <- js }, func() { b.DispatchCallback() }, &Options{Strict: true})
+
+	a.NotError(b.Bind("save", func(v *address, any interface{}) string { return fmt.Sprintf("%T", any) }))
+
+	b.MessageHandler(`{"id":1,"method":"save","params":[{"city":"c"},1]}`)
+	a.Equal(waitEval(a, evals), `window._rpc.settle(1, true, `+decoded(`"json.Number"`)+`)`)
+
+	b.MessageHandler(`{"id":3,"method":"save","params":[{"cty":"c"},1]}`)
+	a.Equal(waitEval(a, evals), `window._rpc.settle(3, false, `+decoded(`{"message":"invalid params: arg0 json: unknown field \"cty\"","code":"invalid_params","data":[{"param":0,"rule":"decode","message":"json: unknown field \"cty\""}]}`)+`)`)
+
+	// 非严格模式
+	b, _, evals = newTestBinder(a)
+	a.NotError(b.Bind("save", func(v *address, any interface{}) string { return fmt.Sprintf("%T", any) }))
+	b.MessageHandler(`{"id":1,"method":"save","params":[{"city":"c","cty":"c"},1]}`)
+	a.Equal(waitEval(a, evals), `window._rpc.settle(1, true, `+decoded(`"float64"`)+`)`)
+}
+
+func TestBinder_origins(t *testing.T) {
+	a := assert.New(t, false)
+	evals := make(chan string, 10)
+	var b *Binder
+	errlog := &bytes.Buffer{}
+	b = NewBinder(&testApp{}, func(js string) { evals <- js }, func() { b.DispatchCallback() }, &Options{
+		Origins: []string{"https://app.example.com"},
+		Error:   log.New(errlog, "", 0),
+	})
+	token := `,"token":"` + b.token + `"`
+	forbidden := func(method string) string {
+		return decoded(`{"message":"origin not allowed: ` + method + `","code":"forbidden"}`)
+	}
+
+	a.NotError(b.Bind("def", func() int { return 1 }))
+	a.NotError(b.Bind("any", webview.WithOrigins(func() int { return 2 }, "*")))
+	a.NotError(b.Bind("local", webview.WithTimeout(webview.WithOrigins(func() int { return 3 }, "HTTP://localhost:8080/"), time.Second)))
+	a.NotError(b.BindObject("obj", webview.WithOrigins(&object{v: 5}, "null"), webview.LowerCamelCase))
+
+	// 前端报告的 origin
+	b.MessageHandler(`{"id":1,"method":"def","params":[],"origin":"https://app.example.com"}`)
+	a.Equal(waitEval(a, evals), `window._rpc.settle(1, true, `+decoded(`1`)+`)`)
+	b.MessageHandler(`{"id":2,"method":"def","params":[],"origin":"https://evil.com"}`)
+	a.Equal(waitEval(a, evals), `window._rpc.settle(2, false, `+forbidden("def")+`)`)
+	b.MessageHandler(`{"id":3,"method":"def","params":[]}`)
+	a.Equal(waitEval(a, evals), `window._rpc.settle(3, false, `+forbidden("def")+`)`)
+	b.MessageHandler(`{"id":4,"method":"any","params":[]}`)
+	a.Equal(waitEval(a, evals), `window._rpc.settle(4, true, `+decoded(`2`)+`)`)
+
+	// 平台提供的地址优先
+	b.HandleMessage(`{"id":5,"method":"def","params":[],"origin":"https://app.example.com"`+token+`}`, Source{URL: "https://evil.com/index.html"})
+	a.Equal(waitEval(a, evals), `window._rpc.settle(5, false, `+forbidden("def")+`)`)
+	b.HandleMessage(`{"id":6,"method":"local","params":[]`+token+`}`, Source{URL: "http://localhost:8080/index.html"})
+	a.Equal(waitEval(a, evals), `window._rpc.settle(6, true, `+decoded(`3`)+`)`)
+
+	// 页面允许，但框架不允许
+	b.HandleMessage(`{"id":7,"method":"local","params":[],"origin":"http://localhost:8080"}`, Source{URL: "http://localhost:8080/", Origin: "https://ads.com"})
+	a.Equal(waitEval(a, evals), `window._rpc.settle(7, false, `+forbidden("local")+`)`)
+
+	// 平台无法提供框架的 origin，未持有令牌的框架伪造 origin。
+	b.HandleMessage(`{"id":8,"method":"def","params":[],"origin":"https://app.example.com"}`, Source{URL: "https://app.example.com/"})
+	b.HandleMessage(`{"id":8,"method":"def","params":[],"origin":"https://app.example.com","token":"x"}`, Source{URL: "https://app.example.com/"})
+	a.Equal(strings.Count(errlog.String(), "untrusted RPC message from https://app.example.com"), 2)
+
+	// BindObject
+	b.HandleMessage(`{"id":9,"method":"obj.get","params":[]`+token+`}`, Source{URL: "about:blank"})
+	a.Equal(waitEval(a, evals), `window._rpc.settle(9, true, `+decoded(`5`)+`)`) // 伪造的 id 8 没有执行
+	b.HandleMessage(`{"id":10,"method":"obj.get","params":[]`+token+`}`, Source{URL: "https://app.example.com"})
+	a.Equal(waitEval(a, evals), `window._rpc.settle(10, false, `+forbidden("obj.get")+`)`)
+
+	// 事件
+	payloads := make(chan string, 10)
+	b.On("idle", func(p json.RawMessage) { payloads <- string(p) })
+	b.MessageHandler(`{"type":"event","event":"idle","payload":1,"origin":"https://evil.com"}`)
+	b.MessageHandler(`{"type":"event","event":"idle","payload":2,"origin":"https://app.example.com"}`)
+	a.Equal(<-payloads, "2")
+}
+
+func TestBinder_callInfo(t *testing.T) {
+	a := assert.New(t, false)
+	b, _, evals := newTestBinder(a)
+	b2, _, _ := newTestBinder(a)
+	a.NotEqual(b.WindowID(), b2.WindowID())
+
+	callers := make(chan *webview.CallInfo, 1)
+	b.middlewares = []webview.Middleware{func(next webview.Handler) webview.Handler {
+		return func(inv *webview.Invocation) (interface{}, error) {
+			callers <- inv.Caller
+			return next(inv)
+		}
+	}}
+
+	a.NotError(b.Bind("info", func(info *webview.CallInfo, ctx context.Context, x int) string {
+		return fmt.Sprintf("%v %s %s %d %v %d", info.WindowID == b.WindowID(), info.URL, info.Origin, info.ID, ctx != nil, x)
+	}))
+	a.NotError(b.Bind("stream", func(info *webview.CallInfo, s webview.Stream) error {
+		return s.Send(info.ID)
+	}))
+	a.NotError(b.Bind("reversed", func(ctx context.Context, info *webview.CallInfo, s webview.Stream, x string) error {
+		return s.Send(fmt.Sprintf("%v %s %s", ctx != nil, info.Origin, x))
+	}))
+
+	b.MessageHandler(`{"id":1,"method":"info","params":[5],"origin":"https://a.com","url":"https://a.com/x"}`)
+	a.Equal(waitEval(a, evals), `window._rpc.settle(1, true, `+decoded(`"true https://a.com/x https://a.com 1 true 5"`)+`)`).
+		Equal(<-callers, &webview.CallInfo{WindowID: b.WindowID(), URL: "https://a.com/x", Origin: "https://a.com", ID: 1})
+
+	// 平台提供的地址优先
+	b.HandleMessage(`{"id":2,"method":"info","params":[6],"url":"https://a.com/x","token":"`+b.token+`"}`, Source{URL: "https://b.com/y"})
+	a.Equal(waitEval(a, evals), `window._rpc.settle(2, true, `+decoded(`"true https://b.com/y https://b.com 2 true 6"`)+`)`)
+	<-callers
+
+	b.MessageHandler(`{"id":3,"method":"info","params":[]}`)
+	a.Equal(waitEval(a, evals), `window._rpc.settle(3, false, `+decoded(`{"message":"function arguments mismatch: info expects 1 arguments, got 0"}`)+`)`)
+	<-callers
+
+	b.MessageHandler(`{"id":4,"method":"stream","params":[]}`)
+	a.Equal(waitEval(a, evals), `window._rpc.push(4, `+decoded(`4`)+`)`).
+		Equal(waitEval(a, evals), `window._rpc.settle(4, true, `+decoded(`null`)+`)`)
+	<-callers
+
+	// context.Context 在 *CallInfo 之前，前端无法伪造 *CallInfo。
+	b.MessageHandler(`{"id":5,"method":"reversed","params":[{"origin":"https://evil.com"}],"origin":"https://a.com"}`)
+	a.Equal(waitEval(a, evals), `window._rpc.settle(5, false, `+decoded(`{"message":"invalid params: arg0 json: cannot unmarshal object into Go value of type string","code":"invalid_params","data":[{"param":0,"rule":"decode","message":"json: cannot unmarshal object into Go value of type string"}]}`)+`)`)
+	<-callers
+
+	b.MessageHandler(`{"id":6,"method":"reversed","params":["x"],"origin":"https://a.com"}`)
+	a.Equal(waitEval(a, evals), `window._rpc.push(6, `+decoded(`"true https://a.com x"`)+`)`).
+		Equal(waitEval(a, evals), `window._rpc.settle(6, true, `+decoded(`null`)+`)`)
+}
+
+func TestBinder_Emit(t *testing.T) {
+	a := assert.New(t, false)
+	b, _, evals := newTestBinder(a)
+
+	a.NotError(b.Emit("progress", map[string]int{"percent": 50}))
+	a.Equal(waitEval(a, evals), `window._rpc.emit("progress", `+decoded(`{"percent":50}`)+`)`)
+
+	a.NotError(b.Emit("done", nil))
+	a.Equal(waitEval(a, evals), `window._rpc.emit("done", `+decoded(`null`)+`)`)
+
+	a.Error(b.Emit("invalid", func() {}))
+}
+
+func TestBinder_Dispatch(t *testing.T) {
+	a := assert.New(t, false)
+
+	// 由单独的 goroutine 模拟主线程
+	wakeup := make(chan struct{}, 100)
+	var b *Binder
+	b = NewBinder(&testApp{}, func(string) {}, func() { wakeup <- struct{}{} }, nil)
+	exit := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-wakeup:
+				b.DispatchCallback()
+			case <-exit:
+				return
+			}
+		}
+	}()
+	defer close(exit)
+
+	// 在执行的函数中再次调用 Dispatch
+	results := make(chan int, 3)
+	b.Dispatch(func() {
+		results <- 1
+		b.Dispatch(func() { results <- 3 })
+	})
+	b.Dispatch(func() { results <- 2 })
+	a.Equal(<-results, 1).Equal(<-results, 2).Equal(<-results, 3)
+
+	v := 0
+	a.NotError(b.DispatchSync(func() {
+		time.Sleep(50 * time.Millisecond)
+		v = 5
+	}))
+	a.Equal(v, 5)
+
+	b.Close()
+	a.Equal(b.DispatchSync(func() { v = 6 }), webview.ErrClosed())
+	b.Dispatch(func() { v = 7 })
+	time.Sleep(50 * time.Millisecond)
+	a.Equal(v, 5)
+}
+
+func TestBinder_On(t *testing.T) {
+	a := assert.New(t, false)
+	b, _, _ := newTestBinder(a)
+
+	payloads := make(chan string, 10)
+	b.On("idle", func(p json.RawMessage) { payloads <- "1:" + string(p) })
+	b.On("idle", func(p json.RawMessage) { payloads <- "2:" + string(p) })
+
+	b.MessageHandler(`{"type":"event","event":"idle","payload":{"seconds":5}}`)
+	a.Equal(<-payloads, `1:{"seconds":5}`).
+		Equal(<-payloads, `2:{"seconds":5}`)
+
+	b.Off("idle")
+	b.MessageHandler(`{"type":"event","event":"idle","payload":1}`)
+	select {
+	case p := <-payloads:
+		a.TB().Fatalf("不应该触发 %s", p)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// 事件不占用 window._rpc 中的位置
+	b.callsM.Lock()
+	a.Empty(b.calls)
+	b.callsM.Unlock()
+}
+
+func TestBinder_Unbind(t *testing.T) {
+	a := assert.New(t, false)
+	b, app, evals := newTestBinder(a)
+
+	a.NotError(b.Bind("f", func() int { return 1 }))
+	a.Length(app.scripts, 2)
+
+	b.Unbind("f")
+	a.Equal(waitEval(a, evals), `delete window["f"]`)
+	b.Unbind("f") // 多次解绑
+
+	b.MessageHandler(`{"id":1,"method":"f","params":[]}`)
+	a.Equal(waitEval(a, evals), `window._rpc.settle(1, false, `+decoded(`{"message":"method not found: f","code":"method_not_found"}`)+`)`)
+
+	b.MessageHandler(`{"type":"load","token":"` + b.token + `"}`)
+	a.Equal(waitEval(a, evals), `delete window["f"];`)
+
+	// 重新绑定
+	a.NotError(b.Bind("f", func() int { return 2 }))
+	a.Length(app.scripts, 2)
+	a.Contains(waitEval(a, evals), `window["f"] = window._rpc.stub("f", false)`)
+
+	b.MessageHandler(`{"id":1,"method":"f","params":[]}`)
+	a.Equal(waitEval(a, evals), `window._rpc.settle(1, true, `+decoded(`2`)+`)`)
+
+	b.MessageHandler(`{"type":"load","token":"` + b.token + `"}`)
+	select {
+	case js := <-evals:
+		a.TB().Fatalf("不应该执行 %s", js)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// 重新绑定为流，之后加载的页面也需要新的定义。
+	b.Unbind("f")
+	a.Equal(waitEval(a, evals), `delete window["f"]`)
+	a.NotError(b.Bind("f", func() <-chan int { return nil }))
+	a.Length(app.scripts, 3).
+		Equal(app.scripts[2], `window["f"] = window._rpc.stub("f", true)`).
+		Equal(waitEval(a, evals), app.scripts[2])
+}
+
+func TestBinder_inject(t *testing.T) {
+	a := assert.New(t, false)
+	app := &testApp{}
+	evals := make(chan string, 10)
+	injected := []string{}
+
+	var b *Binder
+	b = NewBinder(app, func(js string) { evals <- js }, func() { b.DispatchCallback() }, &Options{
+		Error:  log.New(os.Stderr, "", 0),
+		Inject: func(js string) { injected = append(injected, js) },
+	})
+
+	a.NotError(b.Bind("f", func() int { return 1 }))
+	a.NotError(b.Bind("g", func() int { return 1 }))
+	// 仅运行时代码通过 OnLoad 注入
+	a.Length(app.scripts, 1).
+		Length(injected, 2).
+		Equal(injected[1], `window["f"] = window._rpc.stub("f", false);`+"\n"+`window["g"] = window._rpc.stub("g", false)`)
+
+	// 之后加载的页面不再包含 f
+	b.Unbind("f")
+	a.Equal(waitEval(a, evals), `delete window["f"]`).
+		Length(injected, 3).
+		Equal(injected[2], `window["g"] = window._rpc.stub("g", false)`)
+
+	a.NotError(b.Bind("g", func() <-chan int { return nil }))
+	a.Equal(waitEval(a, evals), `window["g"] = window._rpc.stub("g", true)`).
+		Length(injected, 4).
+		Equal(injected[3], `window["g"] = window._rpc.stub("g", true)`)
+
+	b.Unbind("g")
+	a.Equal(waitEval(a, evals), `delete window["g"]`).
+		Length(injected, 5).
+		Empty(injected[4])
+}
+
+func TestBinder_stream(t *testing.T) {
+	a := assert.New(t, false)
+	b, app, evals := newTestBinder(a)
+
+	a.NotError(b.Bind("ch", func(n int) <-chan int {
+		ch := make(chan int)
+		go func() {
+			for i := 0; i < n; i++ {
+				ch <- i
+			}
+			close(ch)
+		}()
+		return ch
+	}))
+	a.Contains(app.scripts[1], `window._rpc.stub("ch", true)`)
+
+	b.MessageHandler(`{"id":1,"method":"ch","params":[2]}`)
+	a.Equal(waitEval(a, evals), `window._rpc.push(1, `+decoded(`0`)+`)`).
+		Equal(waitEval(a, evals), `window._rpc.push(1, `+decoded(`1`)+`)`).
+		Equal(waitEval(a, evals), `window._rpc.settle(1, true, null)`)
+
+	a.NotError(b.Bind("writer", func(ctx context.Context, s webview.Stream, n int) error {
+		for i := 0; i < n; i++ {
+			if err := s.Send(i); err != nil {
+				return err
+			}
+		}
+		return errors.New("end")
+	}))
+	a.Contains(app.scripts[2], `window._rpc.stub("writer", true)`)
+
+	b.MessageHandler(`{"id":2,"method":"writer","params":[1]}`)
+	a.Equal(waitEval(a, evals), `window._rpc.push(2, `+decoded(`0`)+`)`).
+		Equal(waitEval(a, evals), `window._rpc.settle(2, false, `+decoded(`{"message":"end"}`)+`)`)
+
+	// 取消
+	a.NotError(b.Bind("forever", func(ctx context.Context) <-chan int {
+		return make(chan int)
+	}))
+	b.MessageHandler(`{"id":3,"method":"forever","params":[]}`)
+	time.Sleep(50 * time.Millisecond)
+	b.MessageHandler(`{"type":"cancel","id":3,"token":"` + b.token + `"}`)
+	a.Equal(waitEval(a, evals), `window._rpc.settle(3, false, `+decoded(`{"message":"context canceled","code":"canceled"}`)+`)`)
+}
+
+func TestBinder_callback(t *testing.T) {
+	a := assert.New(t, false)
+	b, _, evals := newTestBinder(a)
+
+	cbs := make(chan webview.Callback, 1)
+	a.NotError(b.Bind("scan", func(dir string, progress webview.Callback) error {
+		cbs <- progress
+		return progress.Call(50, dir)
+	}))
+	a.NotError(b.Bind("watch", func(dir string, changed webview.Callback) error {
+		cbs <- changed
+		return changed.Keep()
+	}))
+
+	// 调用结束之后释放
+	b.MessageHandler(`{"id":1,"method":"scan","params":["/home",{"$callback":5}]}`)
+	a.Equal(waitEval(a, evals), `window._rpc.callback(5, [`+decoded(`50`)+`,`+decoded(`"/home"`)+`])`).
+		Equal(waitEval(a, evals), `window._rpc.settle(1, true, `+decoded(`null`)+`)`)
+	cb := <-cbs
+	a.ErrorIs(cb.Call(), webview.ErrCallbackReleased()).
+		ErrorIs(cb.Keep(), webview.ErrCallbackReleased())
+	cb.Release() // 已经释放，不再通知前端。
+	select {
+	case js := <-evals:
+		a.TB().Fatalf("不应该执行 %s", js)
+	default:
+	}
+
+	// 保留
+	b.MessageHandler(`{"id":2,"method":"watch","params":["/home",{"$callback":6}]}`)
+	a.Equal(waitEval(a, evals), `window._rpc.keep(6)`).
+		Equal(waitEval(a, evals), `window._rpc.settle(2, true, `+decoded(`null`)+`)`)
+	cb = <-cbs
+	a.NotError(cb.Call())
+	a.Equal(waitEval(a, evals), `window._rpc.callback(6, [])`)
+	a.NotError(cb.Keep()) // 多次调用
+	cb.Release()
+	a.Equal(waitEval(a, evals), `window._rpc.release(6)`)
+	cb.Release()
+	a.ErrorIs(cb.Call(), webview.ErrCallbackReleased())
+
+	// 页面跳转之后自动释放
+	b.MessageHandler(`{"id":3,"method":"watch","params":["/",{"$callback":7}]}`)
+	waitEval(a, evals)
+	waitEval(a, evals)
+	cb = <-cbs
+	b.MessageHandler(`{"type":"load","token":"` + b.token + `"}`)
+	a.ErrorIs(cb.Call(1), webview.ErrCallbackReleased())
+
+	// 参数不是函数
+	b.MessageHandler(`{"id":1,"method":"scan","params":["/",5]}`)
+	a.Contains(waitEval(a, evals), `window._rpc.settle(1, false,`)
+}
+
+func TestBinder_bytes(t *testing.T) {
+	a := assert.New(t, false)
+	b, _, evals := newTestBinder(a)
+
+	a.NotError(b.Bind("reverse", func(data []byte) []byte {
+		for i, j := 0, len(data)-1; i < j; i, j = i+1, j-1 {
+			data[i], data[j] = data[j], data[i]
+		}
+		return data
+	}))
+
+	// 前端的 Uint8Array 等以 base64 编码传递
+	b.MessageHandler(`{"id":1,"method":"reverse","params":["YWJj"]}`)
+	a.Equal(waitEval(a, evals), `window._rpc.settle(1, true, window._rpc.bytes("Y2Jh"))`)
+}
+
+func TestBinder_codec(t *testing.T) {
+	a := assert.New(t, false)
+	app := &testApp{}
+	evals := make(chan string, 10)
+	var b *Binder
+	b = NewBinder(app, func(js string) { evals <- js }, func() { b.DispatchCallback() }, &Options{
+		Codec: codec.MessagePack(),
+	})
+	a.Length(app.scripts, 1).
+		Contains(app.scripts[0], codec.MessagePack().JS()).
+		Contains(app.scripts[0], "var raw = false;") // 参数以字符串的形式传递
+
+	type point struct {
+		X int `json:"x"`
+		Y int `json:"y"`
+	}
+	a.NotError(b.Bind("move", func(p point, dx int) point { return point{X: p.X + dx, Y: p.Y} }))
+
+	p, err := codec.MessagePack().Marshal(map[string]int{"x": 1, "y": 2})
+	a.NotError(err)
+	dx, err := codec.MessagePack().Marshal(2)
+	a.NotError(err)
+	b.MessageHandler(`{"id":1,"method":"move","params":["` + string(p) + `","` + string(dx) + `"]}`)
+
+	ret, err := codec.MessagePack().Marshal(point{X: 3, Y: 2})
+	a.NotError(err).Equal(waitEval(a, evals), `window._rpc.settle(1, true, `+decoded(string(ret))+`)`)
+
+	// 事件依然以 JSON 格式传递给 On 订阅的函数
+	payloads := make(chan string, 1)
+	b.On("idle", func(p json.RawMessage) { payloads <- string(p) })
+	b.MessageHandler(`{"type":"event","event":"idle","payload":"` + string(p) + `"}`)
+	a.Equal(<-payloads, `{"x":1,"y":2}`)
+}
+
+func TestBinder_middlewares(t *testing.T) {
+	a := assert.New(t, false)
+	app := &testApp{}
+	evals := make(chan string, 10)
+	logs := make(chan string, 10)
+
+	logger := func(next webview.Handler) webview.Handler {
+		return func(inv *webview.Invocation) (interface{}, error) {
+			ret, err := next(inv)
+			logs <- fmt.Sprintln(inv.Method, inv.Params, ret, err)
+			return ret, err
+		}
+	}
+	auth := func(next webview.Handler) webview.Handler {
+		return func(inv *webview.Invocation) (interface{}, error) {
+			if inv.Method == "admin" {
+				return nil, webview.NewError("forbidden", "forbidden", nil)
+			}
+			inv.Params = append(inv.Params, "10") // 修改参数
+			return next(inv)
+		}
+	}
+
+	var b *Binder
+	b = NewBinder(app, func(js string) { evals <- js }, func() { b.DispatchCallback() }, &Options{
+		Middlewares: []webview.Middleware{logger, auth},
+	})
+
+	a.NotError(b.Bind("add", func(x, y int) int { return x + y }))
+	a.NotError(b.Bind("admin", func() {}))
+
+	b.MessageHandler(`{"id":1,"method":"add","params":[1]}`)
+	a.Equal(waitEval(a, evals), `window._rpc.settle(1, true, `+decoded(`11`)+`)`).
+		Equal(<-logs, "add [1 10] 11 <nil>\n")
+
+	b.MessageHandler(`{"id":2,"method":"admin","params":[]}`)
+	a.Equal(waitEval(a, evals), `window._rpc.settle(2, false, `+decoded(`{"message":"forbidden","code":"forbidden"}`)+`)`).
+		Equal(<-logs, "admin [] <nil> forbidden\n")
+}
+
+func TestBinder_panic(t *testing.T) {
+	a := assert.New(t, false)
+	b, _, evals := newTestBinder(a)
+
+	a.NotError(b.Bind("nil", func(m map[string]int) int {
+		var p *int
+		return *p + m["x"]
+	}))
+	b.MessageHandler(`{"id":1,"method":"nil","params":[{}]}`)
+	a.Equal(waitEval(a, evals), `window._rpc.settle(1, false, `+decoded(`{"message":"internal error","code":"internal"}`)+`)`)
+
+	// 中间件中看到的是普通的错误
+	errs := make(chan error, 1)
+	b.middlewares = []webview.Middleware{func(next webview.Handler) webview.Handler {
+		return func(inv *webview.Invocation) (interface{}, error) {
+			if inv.Method == "mw" {
+				panic("middleware")
+			}
+			ret, err := next(inv)
+			errs <- err
+			return ret, err
+		}
+	}}
+	b.MessageHandler(`{"id":2,"method":"nil","params":[{}]}`)
+	a.Contains(waitEval(a, evals), `\"code\":\"internal\"`).
+		ErrorIs(<-errs, webview.ErrInternal())
+
+	// 中间件的 panic
+	b.MessageHandler(`{"id":3,"method":"mw","params":[]}`)
+	a.Contains(waitEval(a, evals), `\"code\":\"internal\"`)
+
+	// 事件
+	payloads := make(chan string, 1)
+	b.On("e", func(json.RawMessage) { panic("event") })
+	b.On("e", func(p json.RawMessage) { payloads <- string(p) })
+	b.MessageHandler(`{"type":"event","event":"e","payload":1}`)
+	a.Equal(<-payloads, "1")
+
+	// RePanic
+	b.rePanic = true
+	a.Panic(func() {
+		var err error
+		defer b.recover("f", &err)
+		panic("f")
+	})
+
+	// RePanic 时经由 invoke 只输出一次日志，且 panic 的值不变。
+	logs := &bytes.Buffer{}
+	b.errlog = log.New(logs, "", 0)
+	b.middlewares = nil
+	a.NotError(b.Bind("str", func() { panic("str") }))
+	func() {
+		defer func() { a.Equal(recover(), "str") }()
+		b.invoke(&request{ctx: context.Background(), method: "str", info: &webview.CallInfo{}})
+	}()
+	a.Equal(strings.Count(logs.String(), "panic in str"), 1)
+}
@@ -0,0 +1,99 @@
+// SPDX-License-Identifier: MIT
+
+package pipe
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/issue9/webview"
+)
+
+// 回调函数的状态
+const (
+	callbackActive   int32 = iota // 在调用结束时释放
+	callbackKept                  // 由 Keep 保留，直到 Release 或是页面跳转。
+	callbackReleased              // 已经释放
+)
+
+// webview.Callback 的实现
+type callback struct {
+	b     *Binder
+	req   *request
+	id    string
+	state int32
+}
+
+func (b *Binder) newCallback(req *request, param string) (*callback, error) {
+	handle := struct {
+		ID *int `json:"$callback"`
+	}{}
+	if err := b.codec.Unmarshal([]byte(param), &handle); err != nil {
+		return nil, err
+	}
+	if handle.ID == nil {
+		return nil, errors.New("parameter is not a function")
+	}
+
+	cb := &callback{b: b, req: req, id: strconv.Itoa(*handle.ID)}
+	req.callbacksM.Lock()
+	defer req.callbacksM.Unlock()
+	if atomic.LoadInt32(&req.settled) != 0 { // 调用已经结束，前端也已经释放。
+		cb.state = callbackReleased
+	}
+	req.callbacks = append(req.callbacks, cb)
+	return cb, nil
+}
+
+// 释放 req 中未被保留的回调函数
+//
+// 仅修改后端的状态，前端在 window._rpc.settle 中自行释放。
+// 调用者需要持有 req.callbacksM，以保证与 Keep 的顺序一致。
+func releaseCallbacks(req *request) {
+	for _, cb := range req.callbacks {
+		atomic.CompareAndSwapInt32(&cb.state, callbackActive, callbackReleased)
+	}
+}
+
+func (cb *callback) released() bool {
+	return atomic.LoadInt32(&cb.state) == callbackReleased || cb.b.currentPage() != cb.req.page || cb.b.ctx.Err() != nil
+}
+
+func (cb *callback) Call(args ...interface{}) error {
+	if cb.released() {
+		return webview.ErrCallbackReleased()
+	}
+
+	params := make([]string, 0, len(args))
+	for _, arg := range args {
+		data, err := cb.b.marshal(arg)
+		if err != nil {
+			return err
+		}
+		params = append(params, data)
+	}
+
+	cb.b.enqueue(cb.req.page, "window._rpc.callback("+cb.id+", ["+strings.Join(params, ",")+"])")
+	return nil
+}
+
+func (cb *callback) Keep() error {
+	cb.req.callbacksM.Lock()
+	defer cb.req.callbacksM.Unlock()
+
+	if cb.released() {
+		return webview.ErrCallbackReleased()
+	}
+	if atomic.CompareAndSwapInt32(&cb.state, callbackActive, callbackKept) {
+		cb.b.enqueue(cb.req.page, "window._rpc.keep("+cb.id+")")
+	}
+	return nil
+}
+
+func (cb *callback) Release() {
+	if atomic.SwapInt32(&cb.state, callbackReleased) != callbackReleased {
+		cb.b.enqueue(cb.req.page, "window._rpc.release("+cb.id+")")
+	}
+}
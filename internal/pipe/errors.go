@@ -0,0 +1,99 @@
+// SPDX-License-Identifier: MIT
+
+package pipe
+
+import (
+	"context"
+	"errors"
+	"runtime/debug"
+
+	"github.com/issue9/webview"
+)
+
+// 返回给前端的错误对象
+type jsError struct {
+	Message string      `json:"message"`
+	Code    string      `json:"code,omitempty"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// 将 err 转换为前端的错误对象
+func (b *Binder) marshalError(err error) string {
+	e := &jsError{Message: err.Error()}
+
+	var ce webview.CodeError
+	switch {
+	case errors.As(err, &ce):
+		e.Code = ce.ErrorCode()
+	case errors.Is(err, context.Canceled):
+		e.Code = webview.ErrorCodeCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		e.Code = webview.ErrorCodeTimeout
+	case errors.Is(err, webview.ErrMethodNotFound()):
+		e.Code = webview.ErrorCodeMethodNotFound
+	case errors.Is(err, webview.ErrInternal()):
+		e.Code = webview.ErrorCodeInternal
+	case errors.Is(err, webview.ErrOriginNotAllowed()):
+		e.Code = webview.ErrorCodeForbidden
+	case errors.Is(err, webview.ErrBusy()):
+		e.Code = webview.ErrorCodeBusy
+	}
+
+	var de webview.DataError
+	if errors.As(err, &de) {
+		e.Data = de.ErrorData()
+	}
+
+	data, err := b.marshal(e)
+	if err != nil { // 只有 Data 可能无法编码
+		b.errlog.Printf("marshal error data %v", err)
+		e.Data = nil
+		if data, err = b.marshal(e); err != nil { // 都是字符串，不会出错。
+			panic(err)
+		}
+	}
+	return data
+}
+
+// 第 param 个参数无法解码的错误
+func decodeError(param int, err error) error {
+	return &webview.ValidationError{Fields: []*webview.FieldError{{Param: param, Rule: "decode", Message: err.Error()}}}
+}
+
+// 已经输出过日志的 panic
+//
+// 嵌套的 recover 在指定了 Options.RePanic 时以此包装之后再 panic，
+// 外层的 recover 不再输出日志，直接以原来的值 panic。
+type loggedPanic struct{ value interface{} }
+
+// 将 panic 转换为 webview.ErrInternal 并写入 err
+//
+// 只能通过 defer 调用，panic 的内容及调用栈会输出到日志，
+// 如果指定了 Options.RePanic，则在输出日志之后重新 panic。
+func (b *Binder) recover(name string, err *error) {
+	if r := recover(); r != nil {
+		b.panicked(name, r, err, false)
+	}
+}
+
+// 与 recover 相同，但是用于嵌套在另一个 recover 之内的调用
+func (b *Binder) recoverNested(name string, err *error) {
+	if r := recover(); r != nil {
+		b.panicked(name, r, err, true)
+	}
+}
+
+func (b *Binder) panicked(name string, r interface{}, err *error, nested bool) {
+	if p, ok := r.(*loggedPanic); ok { // 已经由内层输出了日志
+		panic(p.value)
+	}
+
+	b.errlog.Printf("panic in %s: %v\n%s", name, r, debug.Stack())
+	if b.rePanic {
+		if nested {
+			panic(&loggedPanic{value: r})
+		}
+		panic(r)
+	}
+	*err = webview.ErrInternal()
+}
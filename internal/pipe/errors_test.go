@@ -0,0 +1,37 @@
+// SPDX-License-Identifier: MIT
+
+package pipe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/issue9/assert/v3"
+
+	"github.com/issue9/webview"
+)
+
+func TestBinder_marshalError(t *testing.T) {
+	a := assert.New(t, false)
+	b, _, _ := newTestBinder(a)
+
+	a.Equal(b.marshalError(errors.New("abc")), decoded(`{"message":"abc"}`))
+
+	a.Equal(b.marshalError(context.Canceled), decoded(`{"message":"context canceled","code":"canceled"}`))
+
+	err := fmt.Errorf("%w: f", webview.ErrMethodNotFound())
+	a.Equal(b.marshalError(err), decoded(`{"message":"method not found: f","code":"method_not_found"}`))
+
+	a.Equal(b.marshalError(webview.ErrInternal()), decoded(`{"message":"internal error","code":"internal"}`))
+
+	err = webview.NewError("auth", "no permission", map[string]int{"uid": 1})
+	a.Equal(b.marshalError(err), decoded(`{"message":"no permission","code":"auth","data":{"uid":1}}`))
+
+	err = fmt.Errorf("wrap: %w", err)
+	a.Equal(b.marshalError(err), decoded(`{"message":"wrap: no permission","code":"auth","data":{"uid":1}}`))
+
+	err = webview.NewError("invalid", "invalid data", func() {})
+	a.Equal(b.marshalError(err), decoded(`{"message":"invalid data","code":"invalid"}`))
+}
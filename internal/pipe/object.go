@@ -0,0 +1,101 @@
+// SPDX-License-Identifier: MIT
+
+package pipe
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/issue9/webview"
+)
+
+// BindObject 将 obj 的导出方法绑定至前端的 window[namespace] 对象上
+//
+// 前端通过 window[namespace][name] 调用，name 为经过 mapper 转换之后的方法名，
+// mapper 为空表示采用 Go 中的方法名，mapper 返回空字符串表示不绑定该方法。
+// 如果 obj 实现了 [webview.Excluder] 接口，其返回的方法也不会被绑定。
+// 每个方法的要求与 Bind 相同，只要有一个方法不符合要求，所有方法都不会被绑定。
+//
+// obj 也可以是 [webview.TimeoutFunc] 或 [webview.OriginFunc]，其设置应用于所有方法。
+//
+// 如果 namespace 已经绑定过，旧的方法会被全部解除绑定。
+// 如果 namespace 已经被 Bind 用作方法名，返回 [webview.ErrNameConflict]。
+func (b *Binder) BindObject(namespace string, obj interface{}, mapper webview.NameMapper) error {
+	bd := newBinding(obj)
+	obj = bd.f
+
+	v := reflect.ValueOf(obj)
+	if !v.IsValid() || v.NumMethod() == 0 {
+		return webview.ErrBindObjectNoMethod()
+	}
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return webview.ErrBindObjectNil()
+	}
+
+	excluded := map[string]struct{}{}
+	if e, ok := obj.(webview.Excluder); ok {
+		excluded["ExcludeMethods"] = struct{}{}
+		for _, name := range e.ExcludeMethods() {
+			excluded[name] = struct{}{}
+		}
+	}
+
+	t := v.Type()
+	methods := make(map[string]reflect.Value, t.NumMethod())
+	for i := 0; i < t.NumMethod(); i++ {
+		m := t.Method(i)
+		if _, found := excluded[m.Name]; found {
+			continue
+		}
+
+		name := m.Name
+		if mapper != nil {
+			if name = mapper(name); name == "" {
+				continue
+			}
+		}
+
+		f := v.Method(i)
+		if err := checkFunc(f); err != nil {
+			return fmt.Errorf("%w: %s", err, m.Name)
+		}
+		methods[name] = f
+	}
+	if len(methods) == 0 {
+		return webview.ErrBindObjectNoMethod()
+	}
+
+	b.stubsM.Lock()
+	_, isObject := b.objects[namespace]
+	b.stubsM.Unlock()
+	if _, found := b.bindings.Load(namespace); found && !isObject {
+		return fmt.Errorf("%w: %s", webview.ErrNameConflict(), namespace)
+	}
+
+	b.Unbind(namespace)
+
+	ns := jsString(namespace)
+	names := make([]string, 0, len(methods))
+	for name, f := range methods {
+		full := namespace + "." + name
+		b.bind(full, &binding{f: f.Interface(), timeout: bd.timeout, origins: bd.origins}, &stub{
+			define: "(window[" + ns + "] = window[" + ns + "] || {})[" + jsString(name) + "] = " + invokeJS(full, f.Type()),
+			remove: removeMethodJS(ns, jsString(name)),
+		})
+		names = append(names, full)
+	}
+
+	b.stubsM.Lock()
+	b.objects[namespace] = names
+	b.stubsM.Unlock()
+
+	return nil
+}
+
+// 删除 window[ns][name] 的代码，删除最后一个方法时同时删除 window[ns]。
+//
+// ns 和 name 都是已经由 jsString 转换的字符串。
+func removeMethodJS(ns, name string) string {
+	return "(function(o) { if (!o) { return; } delete o[" + name + "]; " +
+		"if (Object.keys(o).length === 0) { delete window[" + ns + "]; } })(window[" + ns + "])"
+}
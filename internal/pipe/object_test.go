@@ -0,0 +1,92 @@
+// SPDX-License-Identifier: MIT
+
+package pipe
+
+import (
+	"context"
+	"testing"
+
+	"github.com/issue9/assert/v3"
+
+	"github.com/issue9/webview"
+)
+
+type object struct{ v int }
+
+func (o *object) Get() int { return o.v }
+
+func (o *object) Add(ctx context.Context, v int) (int, error) { return o.v + v, nil }
+
+func (o *object) Internal() string { return "internal" }
+
+func (o *object) ExcludeMethods() []string { return []string{"Internal"} }
+
+type streamObject struct{}
+
+func (o *streamObject) Get() <-chan int { return nil }
+
+type invalidObject struct{}
+
+func (o *invalidObject) Get() (int, int) { return 1, 1 }
+
+func TestBinder_BindObject(t *testing.T) {
+	a := assert.New(t, false)
+	b, app, evals := newTestBinder(a)
+
+	a.ErrorIs(b.BindObject("obj", nil, nil), webview.ErrBindObjectNoMethod())
+	a.ErrorIs(b.BindObject("obj", 5, nil), webview.ErrBindObjectNoMethod())
+	a.ErrorIs(b.BindObject("obj", &invalidObject{}, nil), webview.ErrBindFuncReturnInvalid())
+	a.ErrorIs(b.BindObject("obj", (*object)(nil), nil), webview.ErrBindObjectNil())
+	a.Length(app.scripts, 1)
+
+	a.NotError(b.BindObject("obj", &object{v: 5}, webview.LowerCamelCase))
+	a.Length(app.scripts, 3)
+
+	b.MessageHandler(`{"id":1,"method":"obj.get","params":[]}`)
+	a.Equal(waitEval(a, evals), `window._rpc.settle(1, true, `+decoded(`5`)+`)`)
+
+	b.MessageHandler(`{"id":2,"method":"obj.add","params":[2]}`)
+	a.Equal(waitEval(a, evals), `window._rpc.settle(2, true, `+decoded(`7`)+`)`)
+
+	b.MessageHandler(`{"id":3,"method":"obj.internal","params":[]}`)
+	a.Contains(waitEval(a, evals), `\"code\":\"method_not_found\"`)
+
+	b.Unbind("obj")
+	a.Equal(removeAll(waitEval(a, evals), waitEval(a, evals)), removeAll(
+		`(function(o) { if (!o) { return; } delete o["add"]; if (Object.keys(o).length === 0) { delete window["obj"]; } })(window["obj"])`,
+		`(function(o) { if (!o) { return; } delete o["get"]; if (Object.keys(o).length === 0) { delete window["obj"]; } })(window["obj"])`,
+	))
+
+	b.MessageHandler(`{"id":4,"method":"obj.get","params":[]}`)
+	a.Contains(waitEval(a, evals), `\"code\":\"method_not_found\"`)
+
+	// 重新绑定，get 变为流。
+	a.NotError(b.BindObject("obj", &streamObject{}, webview.LowerCamelCase))
+	a.Length(app.scripts, 4).
+		Equal(app.scripts[3], `(window["obj"] = window["obj"] || {})["get"] = window._rpc.stub("obj.get", true)`)
+}
+
+// 方法的删除顺序不固定
+func removeAll(js ...string) map[string]bool {
+	m := make(map[string]bool, len(js))
+	for _, s := range js {
+		m[s] = true
+	}
+	return m
+}
+
+func TestBinder_BindObject_conflict(t *testing.T) {
+	a := assert.New(t, false)
+	b, _, _ := newTestBinder(a)
+
+	a.NotError(b.Bind("f", func() {}))
+	a.ErrorIs(b.BindObject("f", &object{}, nil), webview.ErrNameConflict())
+	a.NotError(b.BindObject("obj", &object{}, nil))
+	a.ErrorIs(b.Bind("obj", func() {}), webview.ErrNameConflict())
+
+	// 解除绑定之后可以再次使用
+	b.Unbind("f")
+	a.NotError(b.BindObject("f", &object{}, nil))
+	b.Unbind("obj")
+	a.NotError(b.Bind("obj", func() {}))
+}
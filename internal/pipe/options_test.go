@@ -0,0 +1,39 @@
+// SPDX-License-Identifier: MIT
+
+package pipe
+
+import (
+	"log"
+	"testing"
+
+	"github.com/issue9/assert/v3"
+
+	"github.com/issue9/webview"
+
+	"github.com/issue9/webview/internal/presets"
+)
+
+func TestSanitizeOptions(t *testing.T) {
+	a := assert.New(t, false)
+
+	o := sanitizeOptions(nil)
+	a.Equal(o.Error, log.Default()).
+		Equal(o.Workers, presets.Workers).
+		Equal(o.MethodWorkers, presets.Workers).
+		NotNil(o.MethodNotFound)
+
+	o = sanitizeOptions(&Options{Workers: 5, MethodWorkers: 2})
+	a.Equal(o.Workers, 5).
+		Equal(o.MethodWorkers, 2)
+
+	o = sanitizeOptions(&Options{Workers: 5, MethodWorkers: 10})
+	a.Equal(o.Workers, 5).
+		Equal(o.MethodWorkers, 5)
+
+	a.PanicString(func() {
+		sanitizeOptions(&Options{Strict: true, Codec: &testCodec{}})
+	}, "StrictCodec")
+}
+
+// 未实现 webview.StrictCodec 的 Codec
+type testCodec struct{ webview.Codec }
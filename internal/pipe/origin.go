@@ -0,0 +1,70 @@
+// SPDX-License-Identifier: MIT
+
+package pipe
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Source 由平台提供的消息来源
+//
+// 各字段为空表示平台无法提供，此时采用前端报告的值。
+// 如果仅提供了 URL，前端报告的 origin 不可信，只接受顶层页面中的运行时发送的消息。
+type Source struct {
+	URL    string // 顶层页面的地址
+	Origin string // 发送消息的框架的 origin
+}
+
+// 规范化 origin
+//
+// 统一为小写并去掉末尾的 /，与 location.origin 相同，默认的端口号也会被去掉。
+func normalizeOrigin(origin string) string {
+	origin = strings.TrimSuffix(strings.ToLower(origin), "/")
+	switch {
+	case strings.HasPrefix(origin, "http://") && strings.HasSuffix(origin, ":80"):
+		return strings.TrimSuffix(origin, ":80")
+	case strings.HasPrefix(origin, "https://") && strings.HasSuffix(origin, ":443"):
+		return strings.TrimSuffix(origin, ":443")
+	}
+	return origin
+}
+
+func normalizeOrigins(origins []string) []string {
+	if len(origins) == 0 {
+		return nil
+	}
+
+	ret := make([]string, 0, len(origins))
+	for _, o := range origins {
+		ret = append(ret, normalizeOrigin(o))
+	}
+	return ret
+}
+
+// 获取地址 u 的 origin
+//
+// file 协议的地址返回 file://，其它没有主机名的地址，比如 about:blank 和 data: 等，返回 null。
+func urlOrigin(u string) string {
+	uu, err := url.Parse(u)
+	switch {
+	case err != nil || uu.Scheme == "":
+		return "null"
+	case uu.Host != "":
+		return normalizeOrigin(uu.Scheme + "://" + uu.Host)
+	case strings.EqualFold(uu.Scheme, "file"):
+		return "file://"
+	default:
+		return "null"
+	}
+}
+
+// origin 是否在 origins 之中
+func matchOrigin(origins []string, origin string) bool {
+	for _, o := range origins {
+		if o == "*" || o == origin {
+			return true
+		}
+	}
+	return false
+}
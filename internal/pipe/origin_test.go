@@ -0,0 +1,51 @@
+// SPDX-License-Identifier: MIT
+
+package pipe
+
+import (
+	"testing"
+
+	"github.com/issue9/assert/v3"
+)
+
+func TestNormalizeOrigin(t *testing.T) {
+	a := assert.New(t, false)
+
+	a.Equal(normalizeOrigin("https://Example.com/"), "https://example.com").
+		Equal(normalizeOrigin("https://example.com:443"), "https://example.com").
+		Equal(normalizeOrigin("http://example.com:80"), "http://example.com").
+		Equal(normalizeOrigin("http://example.com:443"), "http://example.com:443").
+		Equal(normalizeOrigin("null"), "null").
+		Equal(normalizeOrigin("*"), "*")
+
+	a.Nil(normalizeOrigins(nil)).
+		Equal(normalizeOrigins([]string{"HTTPS://a.com/", "*"}), []string{"https://a.com", "*"})
+}
+
+func TestURLOrigin(t *testing.T) {
+	a := assert.New(t, false)
+
+	a.Equal(urlOrigin("https://example.com/path?q=1"), "https://example.com").
+		Equal(urlOrigin("https://example.com:443/"), "https://example.com").
+		Equal(urlOrigin("http://localhost:8080/index.html"), "http://localhost:8080").
+		Equal(urlOrigin("file:///home/index.html"), "file://").
+		Equal(urlOrigin("about:blank"), "null").
+		Equal(urlOrigin("data:text/html,<p>"), "null").
+		Equal(urlOrigin("%"), "null").
+		Equal(urlOrigin(""), "null")
+}
+
+func TestMatchOrigin(t *testing.T) {
+	a := assert.New(t, false)
+
+	a.True(matchOrigin([]string{"https://a.com"}, "https://a.com")).
+		False(matchOrigin([]string{"https://a.com"}, "https://b.com")).
+		True(matchOrigin([]string{"https://a.com", "*"}, "https://b.com")).
+		False(matchOrigin(nil, "https://a.com")).
+		False(matchOrigin([]string{"https://a.com"}, ""))
+
+	a.True(permitted(nil, "", "")).
+		True(permitted([]string{"https://a.com"}, "https://a.com", "https://a.com")).
+		False(permitted([]string{"https://a.com"}, "https://a.com", "https://evil.com")).
+		False(permitted([]string{"https://a.com"}, "https://evil.com", "https://a.com"))
+}
@@ -4,12 +4,148 @@
 package pipe
 
 import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
 	"reflect"
 	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/issue9/webview"
+)
+
+var (
+	errorType     = reflect.TypeOf((*error)(nil)).Elem()
+	contextType   = reflect.TypeOf((*context.Context)(nil)).Elem()
+	callInfoType  = reflect.TypeOf((*webview.CallInfo)(nil))
+	streamType    = reflect.TypeOf((*webview.Stream)(nil)).Elem()
+	callbackType  = reflect.TypeOf((*webview.Callback)(nil)).Elem()
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
 )
 
-var errorType = reflect.TypeOf((*error)(nil)).Elem()
+// 前端的一次调用
+type request struct {
+	ctx     context.Context
+	page    int // 发起调用的页面
+	id      int // 前端 window._rpc 中的序号
+	method  string
+	params  []string // 由 Codec 编码的参数
+	settled int32    // 是否已经将结果传递给前端
+
+	callbacksM sync.Mutex
+	callbacks  []*callback // 参数中的回调函数，调用结束时释放未被保留的。
+
+	pageOrigin string            // 顶层页面的 origin
+	info       *webview.CallInfo // 调用方的信息
+}
+
+// 绑定的方法
+type binding struct {
+	f       interface{}
+	timeout time.Duration // 默认的超时时间，为 0 表示采用 Options.Timeout。
+	origins []string      // 允许调用的来源，为 nil 表示采用 Options.Origins。
+}
+
+// 从 f 中分离出 webview.TimeoutFunc 等包装的设置，多次包装时以外层的为准。
+func newBinding(f interface{}) *binding {
+	bd := &binding{}
+	for {
+		switch w := f.(type) {
+		case *webview.TimeoutFunc:
+			if bd.timeout == 0 {
+				bd.timeout = w.Timeout
+			}
+			f = w.Func
+		case *webview.OriginFunc:
+			if bd.origins == nil {
+				bd.origins = normalizeOrigins(w.Origins)
+			}
+			f = w.Func
+		default:
+			bd.f = f
+			return bd
+		}
+	}
+}
+
+const hex = "0123456789abcdef"
+
+// 将 s 转换为 JS 的字符串字面量
+//
+// 转义规则与 encoding/json 相同，输出可同时作为 JSON 和 JS 的字符串：
+// 控制字符、引号和反斜杠会被转义，非法的 UTF-8 字符被替换为 U+FFFD；
+// 行终止符 U+2028 和 U+2029 以及 <、> 和 & 也会被转义，
+// 防止字符串出现在 <script> 中时被提前结束。
+func jsString(s string) string {
+	buf := &strings.Builder{}
+	buf.Grow(len(s) + 2)
+	buf.WriteByte('"')
+
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' && c != '<' && c != '>' && c != '&' {
+				i++
+				continue
+			}
+
+			buf.WriteString(s[start:i])
+			switch c {
+			case '"', '\\':
+				buf.WriteByte('\\')
+				buf.WriteByte(c)
+			case '\n':
+				buf.WriteString(`\n`)
+			case '\r':
+				buf.WriteString(`\r`)
+			case '\t':
+				buf.WriteString(`\t`)
+			default:
+				buf.WriteString(`\u00`)
+				buf.WriteByte(hex[c>>4])
+				buf.WriteByte(hex[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			buf.WriteString(s[start:i])
+			buf.WriteString("\ufffd")
+		case r == '\u2028' || r == '\u2029':
+			buf.WriteString(s[start:i])
+			buf.WriteString(`\u202`)
+			buf.WriteByte(hex[r&0xf])
+		default:
+			i += size
+			continue
+		}
+		i += size
+		start = i
+	}
+
+	buf.WriteString(s[start:])
+	buf.WriteByte('"')
+	return buf.String()
+}
+
+// 将 v 编码为前端可直接执行的表达式
+//
+// []byte 会被直接转换为 Uint8Array，其它类型则由 Codec 编码之后在前端解码。
+func (b *Binder) marshal(v interface{}) (string, error) {
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8 &&
+		!rv.IsNil() && !rv.Type().Implements(marshalerType) {
+		return "window._rpc.bytes(\"" + base64.StdEncoding.EncodeToString(rv.Bytes()) + "\")", nil
+	}
 
-func jsString(v string) string {
-	return `"` + strings.ReplaceAll(v, "\"", "\\\"") + `"`
+	data, err := b.codec.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return "window._rpc.decode(" + jsString(string(data)) + ")", nil
 }
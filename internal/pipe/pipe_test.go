@@ -4,7 +4,10 @@ package pipe
 
 import (
 	"encoding/json"
+	"errors"
+	"strings"
 	"testing"
+	"unicode/utf8"
 
 	"github.com/issue9/assert/v3"
 )
@@ -12,15 +15,129 @@ import (
 func TestJSString(t *testing.T) {
 	a := assert.New(t, false)
 
-	val := "abc"
-	b, err := json.Marshal(val)
-	a.NotError(err).Equal(string(b), jsString(val))
+	for _, val := range []string{
+		"abc",
+		"abc\"",
+		"abc'",
+		`a\b`,
+		"a\nb\r\tc\x00\x1f",
+		"</script><script>alert(1)</script>",
+		"a&b",
+		"\u2028\u2029",
+		"中文",
+		"\xff\xfe",
+		"a\xc3",
+	} {
+		b, err := json.Marshal(val)
+		a.NotError(err).Equal(string(b), jsString(val), "%q", val)
+	}
 
-	val = "abc\""
-	b, err = json.Marshal(val)
-	a.NotError(err).Equal(string(b), jsString(val))
+	a.Equal(jsString(`"]; alert(1); ["`), `"\"]; alert(1); [\""`)
+}
+
+// 检测 s 是否为可以安全地嵌入 eval 代码中的字符串字面量，并返回其值。
+func checkJSString(t *testing.T, s string) string {
+	t.Helper()
+
+	if strings.ContainsAny(s, "\n\r\u2028\u2029<>") {
+		t.Fatalf("%s 包含未转义的字符", s)
+	}
+
+	var v string
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatalf("%s 无法解析：%s", s, err)
+	}
+	return v
+}
+
+// 解析 s 开头的字符串字面量，返回其值以及之后的内容。
+func parseJSString(t *testing.T, s string) (string, string) {
+	t.Helper()
+
+	d := json.NewDecoder(strings.NewReader(s))
+	var v json.RawMessage
+	if err := d.Decode(&v); err != nil {
+		t.Fatalf("%s 无法解析：%s", s, err)
+	}
+	n := int(d.InputOffset())
+	return checkJSString(t, s[:n]), s[n:]
+}
+
+func FuzzJSString(f *testing.F) {
+	for _, s := range []string{"abc", `a"b\c`, "a\nb", "\u2028", "</script>", "\xff", `"]; alert(1); //`, "] = "} {
+		f.Add(s, s)
+	}
+
+	var b *Binder // 忽略 eval，Unbind 等不会因为 evals 已满而阻塞。
+	b = NewBinder(&testApp{}, func(string) {}, func() { b.DispatchCallback() }, nil)
+
+	f.Fuzz(func(t *testing.T, name, msg string) {
+		if v := checkJSString(t, jsString(name)); utf8.ValidString(name) && v != name {
+			t.Fatalf("解析的值 %q 与原值 %q 不同", v, name)
+		}
+
+		// 绑定方法的声明，解析出的属性名和方法名都应该与原值相同。
+		if err := b.Bind(name, func() {}); err != nil {
+			t.Fatal(err)
+		}
+		b.stubsM.Lock()
+		define := b.stubs[name].define
+		b.stubsM.Unlock()
+		b.Unbind(name)
+		if !strings.HasPrefix(define, "window[") {
+			t.Fatalf("无效的声明 %s", define)
+		}
+		key, rest := parseJSString(t, strings.TrimPrefix(define, "window["))
+		if !strings.HasPrefix(rest, "] = window._rpc.stub(") {
+			t.Fatalf("无效的声明 %s", define)
+		}
+		method, rest := parseJSString(t, strings.TrimPrefix(rest, "] = window._rpc.stub("))
+		if rest != ", false)" {
+			t.Fatalf("无效的声明 %s", define)
+		}
+		if utf8.ValidString(name) && (key != name || method != name) {
+			t.Fatalf("声明 %s 中的名称与原值 %q 不同", define, name)
+		}
+
+		// 错误信息
+		js := b.marshalError(errors.New(msg))
+		if !strings.HasPrefix(js, "window._rpc.decode(") || !strings.HasSuffix(js, ")") {
+			t.Fatalf("无效的表达式 %s", js)
+		}
+		data := checkJSString(t, strings.TrimSuffix(strings.TrimPrefix(js, "window._rpc.decode("), ")"))
+		e := &jsError{}
+		if err := json.Unmarshal([]byte(data), e); err != nil {
+			t.Fatalf("%s 无法解析：%s", data, err)
+		}
+		if utf8.ValidString(msg) && e.Message != msg {
+			t.Fatalf("解析的值 %q 与原值 %q 不同", e.Message, msg)
+		}
+	})
+}
+
+func TestBinder_marshal(t *testing.T) {
+	a := assert.New(t, false)
+	b, _, _ := newTestBinder(a)
+
+	data, err := b.marshal([]byte("abc"))
+	a.NotError(err).Equal(data, `window._rpc.bytes("YWJj")`)
+
+	type bytes []byte
+	data, err = b.marshal(bytes("abc"))
+	a.NotError(err).Equal(data, `window._rpc.bytes("YWJj")`)
+
+	data, err = b.marshal([]byte(nil))
+	a.NotError(err).Equal(data, `window._rpc.decode("null")`)
+
+	data, err = b.marshal(map[string][]byte{"k": []byte("abc")})
+	a.NotError(err).Equal(data, `window._rpc.decode("{\"k\":\"YWJj\"}")`)
+
+	data, err = b.marshal(json.RawMessage(`{"k":1}`))
+	a.NotError(err).Equal(data, `window._rpc.decode("{\"k\":1}")`)
+
+	data, err = b.marshal("</script>\u2028")
+	a.NotError(err).Equal(data, `window._rpc.decode("\"\\u003c/script\\u003e\\u2028\"")`)
 
-	val = "abc'"
-	b, err = json.Marshal(val)
-	a.NotError(err).Equal(string(b), jsString(val))
+	_, err = b.marshal(func() {})
+	a.Error(err)
 }
@@ -0,0 +1,105 @@
+// SPDX-License-Identifier: MIT
+
+package pipe
+
+import (
+	"context"
+	"sync"
+)
+
+// 限制绑定方法并发数量的执行池
+type pool struct {
+	global  chan struct{}
+	pending chan struct{} // 正在执行和等待执行的数量，限制了 goroutine 的数量。
+
+	method   int
+	methodsM *sync.Mutex
+	methods  map[string]*semaphore
+}
+
+// 单个方法的信号量
+//
+// refs 为正在执行和等待执行的数量，为 0 时从 pool.methods 中删除，
+// 防止前端以任意的方法名无限增加 pool.methods 的大小。
+type semaphore struct {
+	ch   chan struct{}
+	refs int
+}
+
+func newPool(global, method, queue int) *pool {
+	return &pool{
+		global:  make(chan struct{}, global),
+		pending: make(chan struct{}, global+queue),
+
+		method:   method,
+		methodsM: &sync.Mutex{},
+		methods:  make(map[string]*semaphore, 10),
+	}
+}
+
+// 申请进入执行池
+//
+// 不会阻塞，执行池已满时返回 false，成功时需要在结束之后调用 leave。
+// 在启动执行绑定方法的 goroutine 之前调用，以限制 goroutine 的数量。
+func (p *pool) enter() bool {
+	select {
+	case p.pending <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *pool) leave() { <-p.pending }
+
+// 获取 name 的信号量，用完之后需要调用 unref。
+func (p *pool) ref(name string) *semaphore {
+	p.methodsM.Lock()
+	defer p.methodsM.Unlock()
+
+	sem, found := p.methods[name]
+	if !found {
+		sem = &semaphore{ch: make(chan struct{}, p.method)}
+		p.methods[name] = sem
+	}
+	sem.refs++
+	return sem
+}
+
+func (p *pool) unref(name string, sem *semaphore) {
+	p.methodsM.Lock()
+	defer p.methodsM.Unlock()
+
+	if sem.refs--; sem.refs == 0 {
+		delete(p.methods, name)
+	}
+}
+
+// 为 name 申请一个执行位置
+//
+// 在取得位置之前会一直阻塞，除非 ctx 被取消。
+// 成功时返回的函数用于释放该位置。
+func (p *pool) acquire(ctx context.Context, name string) (func(), error) {
+	// 先取得方法级别的位置，防止占用了全局位置却在等待方法级别的位置。
+	sem := p.ref(name)
+	select {
+	case sem.ch <- struct{}{}:
+	case <-ctx.Done():
+		p.unref(name, sem)
+		return nil, ctx.Err()
+	}
+
+	select {
+	case p.global <- struct{}{}:
+	case <-ctx.Done():
+		<-sem.ch
+		p.unref(name, sem)
+		return nil, ctx.Err()
+	}
+
+	return func() {
+		<-p.global
+		<-sem.ch
+		p.unref(name, sem)
+	}, nil
+}
@@ -0,0 +1,85 @@
+// SPDX-License-Identifier: MIT
+
+package pipe
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v3"
+)
+
+func TestPool_acquire(t *testing.T) {
+	a := assert.New(t, false)
+	p := newPool(2, 1, 0)
+	ctx := context.Background()
+
+	r1, err := p.acquire(ctx, "m1")
+	a.NotError(err).NotNil(r1)
+
+	// m1 已达上限
+	c, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	r, err := p.acquire(c, "m1")
+	a.ErrorIs(err, context.DeadlineExceeded).Nil(r)
+
+	r2, err := p.acquire(ctx, "m2")
+	a.NotError(err).NotNil(r2)
+
+	// 全局已达上限
+	c, cancel = context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	r, err = p.acquire(c, "m3")
+	a.ErrorIs(err, context.DeadlineExceeded).Nil(r)
+
+	r1()
+	r3, err := p.acquire(ctx, "m3")
+	a.NotError(err).NotNil(r3)
+
+	r2()
+	r3()
+	a.Equal(len(p.global), 0).
+		Empty(p.methods) // 释放之后不再保留
+}
+
+func TestPool_methods(t *testing.T) {
+	a := assert.New(t, false)
+	p := newPool(2, 1, 0)
+	ctx := context.Background()
+
+	for i := 0; i < 100; i++ {
+		r, err := p.acquire(ctx, fmt.Sprintf("m%d", i))
+		a.NotError(err)
+		r()
+	}
+	a.Empty(p.methods)
+
+	// 等待中的信号量不会被删除
+	r1, err := p.acquire(ctx, "m")
+	a.NotError(err)
+	acquired := make(chan func(), 1)
+	go func() {
+		r, err := p.acquire(ctx, "m")
+		a.NotError(err)
+		acquired <- r
+	}()
+	time.Sleep(50 * time.Millisecond)
+	r1()
+	r2 := <-acquired
+	a.Length(p.methods, 1)
+	r2()
+	a.Empty(p.methods)
+}
+
+func TestPool_enter(t *testing.T) {
+	a := assert.New(t, false)
+	p := newPool(2, 1, 1)
+
+	a.True(p.enter()).True(p.enter()).True(p.enter()).
+		False(p.enter()) // Workers + Queue
+
+	p.leave()
+	a.True(p.enter()).False(p.enter())
+}
@@ -0,0 +1,70 @@
+// SPDX-License-Identifier: MIT
+
+package pipe
+
+import (
+	"reflect"
+	"strconv"
+
+	"github.com/issue9/webview/internal/dts"
+)
+
+// webview.Stream 的实现
+type stream struct {
+	b   *Binder
+	req *request
+}
+
+func (s *stream) Send(v interface{}) error {
+	if err := s.req.ctx.Err(); err != nil {
+		return err
+	}
+
+	data, err := s.b.marshal(v)
+	if err != nil {
+		return err
+	}
+
+	s.b.enqueue(s.req.page, "window._rpc.push("+strconv.Itoa(s.req.id)+", "+data+")")
+	return nil
+}
+
+// t 是否为以流的形式返回数据的函数
+func isStream(t reflect.Type) bool {
+	if t.NumOut() > 0 && t.Out(0).Kind() == reflect.Chan && t.Out(0).ChanDir() == reflect.RecvDir {
+		return true
+	}
+
+	_, stream := dts.InjectedParams(t) // Stream 只能是第一个参数或是在 *webview.CallInfo 和 context.Context 之后
+	return stream
+}
+
+// 将 ch 中的数据推送给前端，直到 ch 被关闭或是调用被取消。
+func (b *Binder) drain(req *request, ch reflect.Value) {
+	if ch.IsNil() {
+		b.settle(req, true, "null")
+		return
+	}
+
+	s := &stream{b: b, req: req}
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(req.ctx.Done())},
+		{Dir: reflect.SelectRecv, Chan: ch},
+	}
+	for {
+		chosen, v, ok := reflect.Select(cases)
+		if chosen == 0 {
+			b.settle(req, false, b.marshalError(req.ctx.Err()))
+			return
+		}
+		if !ok {
+			b.settle(req, true, "null")
+			return
+		}
+
+		if err := s.Send(v.Interface()); err != nil {
+			b.settle(req, false, b.marshalError(err))
+			return
+		}
+	}
+}
@@ -0,0 +1,38 @@
+// SPDX-License-Identifier: MIT
+
+package pipe
+
+import (
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/issue9/webview/internal/dts"
+)
+
+// TypeScript 将所有绑定方法的 TypeScript 声明写入 w
+func (b *Binder) TypeScript(w io.Writer) error {
+	g := dts.New()
+
+	methods := map[string]struct{}{} // 属于 BindObject 的方法
+	b.stubsM.Lock()
+	for ns, names := range b.objects {
+		for _, name := range names {
+			if bd, found := b.bindings.Load(name); found {
+				g.Method(ns, strings.TrimPrefix(name, ns+"."), reflect.TypeOf(bd.(*binding).f))
+				methods[name] = struct{}{}
+			}
+		}
+	}
+	b.stubsM.Unlock()
+
+	b.bindings.Range(func(name, bd interface{}) bool {
+		if _, found := methods[name.(string)]; !found {
+			g.Func(name.(string), reflect.TypeOf(bd.(*binding).f))
+		}
+		return true
+	})
+
+	_, err := g.WriteTo(w)
+	return err
+}
@@ -0,0 +1,404 @@
+// SPDX-License-Identifier: MIT
+
+package pipe
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/issue9/webview"
+)
+
+var validatorType = reflect.TypeOf((*webview.Validator)(nil)).Elem()
+
+// 单条验证规则
+type rule struct {
+	name    string
+	message string
+	check   func(v reflect.Value) bool
+}
+
+// 带验证规则的结构体字段
+type fieldRules struct {
+	name      string
+	index     int
+	embedded  bool // 嵌入的结构体，其字段路径不包含自身的名称。
+	omitEmpty bool
+	required  bool
+	rules     []*rule
+}
+
+type structRules struct {
+	fields []*fieldRules
+	err    error
+}
+
+var rulesCache = &sync.Map{}
+
+// 检测 t 中所有结构体的 validate 标签是否合法
+func checkRules(t reflect.Type) error {
+	return walkType(t, map[reflect.Type]struct{}{})
+}
+
+func walkType(t reflect.Type, visited map[reflect.Type]struct{}) error {
+	if _, found := visited[t]; found {
+		return nil
+	}
+	visited[t] = struct{}{}
+
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return walkType(t.Elem(), visited)
+	case reflect.Struct:
+		sr := getStructRules(t)
+		if sr.err != nil {
+			return sr.err
+		}
+		for _, f := range sr.fields {
+			if err := walkType(t.Field(f.index).Type, visited); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// 获取结构体 t 的验证规则
+func getStructRules(t reflect.Type) *structRules {
+	if sr, found := rulesCache.Load(t); found {
+		return sr.(*structRules)
+	}
+
+	sr := &structRules{fields: make([]*fieldRules, 0, t.NumField())}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() && !f.Anonymous {
+			continue
+		}
+
+		name := f.Tag.Get("json")
+		if name == "-" {
+			continue
+		}
+		if index := strings.IndexByte(name, ','); index >= 0 {
+			name = name[:index]
+		}
+
+		fr := &fieldRules{name: name, index: i, embedded: f.Anonymous && name == ""}
+		if fr.name == "" {
+			fr.name = f.Name
+		}
+		if !f.IsExported() && !fr.embedded {
+			continue
+		}
+
+		if err := fr.parse(f.Tag.Get("validate"), f.Type); err != nil {
+			sr.err = fmt.Errorf("%s.%s: %w", t, f.Name, err)
+			break
+		}
+		sr.fields = append(sr.fields, fr)
+	}
+
+	rulesCache.Store(t, sr)
+	return sr
+}
+
+func (fr *fieldRules) parse(tag string, t reflect.Type) error {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	for tag != "" {
+		item := tag
+		if strings.HasPrefix(tag, "regexp=") { // 正则中可能包含逗号，只能是最后一个规则。
+			tag = ""
+		} else if index := strings.IndexByte(tag, ','); index >= 0 {
+			item, tag = tag[:index], tag[index+1:]
+		} else {
+			tag = ""
+		}
+
+		name, param := item, ""
+		if index := strings.IndexByte(item, '='); index >= 0 {
+			name, param = item[:index], item[index+1:]
+		}
+
+		switch name {
+		case "omitempty":
+			fr.omitEmpty = true
+		case "required":
+			fr.required = true
+		default:
+			r, err := newRule(name, param, t)
+			if err != nil {
+				return err
+			}
+			fr.rules = append(fr.rules, r)
+		}
+	}
+	return nil
+}
+
+func newRule(name, param string, t reflect.Type) (*rule, error) {
+	switch name {
+	case "min", "max":
+		n, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value %q", name, param)
+		}
+
+		var value func(reflect.Value) float64
+		var msg string
+		switch {
+		case isNumber(t.Kind()):
+			value = number
+			msg = "must be"
+		case hasLength(t.Kind()):
+			value = func(v reflect.Value) float64 { return float64(length(v)) }
+			msg = "length must be"
+		default:
+			return nil, fmt.Errorf("rule %s does not support type %s", name, t)
+		}
+
+		if name == "min" {
+			return &rule{name: name, message: msg + " at least " + param, check: func(v reflect.Value) bool { return value(v) >= n }}, nil
+		}
+		return &rule{name: name, message: msg + " at most " + param, check: func(v reflect.Value) bool { return value(v) <= n }}, nil
+
+	case "len":
+		n, err := strconv.Atoi(param)
+		if err != nil {
+			return nil, fmt.Errorf("invalid len value %q", param)
+		}
+		if !hasLength(t.Kind()) {
+			return nil, fmt.Errorf("rule len does not support type %s", t)
+		}
+		return &rule{name: name, message: "length must be " + param, check: func(v reflect.Value) bool { return length(v) == n }}, nil
+
+	case "oneof":
+		if t.Kind() != reflect.String && !isNumber(t.Kind()) {
+			return nil, fmt.Errorf("rule oneof does not support type %s", t)
+		}
+		values := strings.Fields(param)
+		if len(values) == 0 {
+			return nil, errors.New("rule oneof requires values")
+		}
+		return &rule{name: name, message: "must be one of " + strings.Join(values, ", "), check: func(v reflect.Value) bool {
+			s := format(v)
+			for _, val := range values {
+				if val == s {
+					return true
+				}
+			}
+			return false
+		}}, nil
+
+	case "regexp":
+		if t.Kind() != reflect.String {
+			return nil, fmt.Errorf("rule regexp does not support type %s", t)
+		}
+		expr, err := regexp.Compile(param)
+		if err != nil {
+			return nil, err
+		}
+		return &rule{name: name, message: "must match " + param, check: func(v reflect.Value) bool { return expr.MatchString(v.String()) }}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown validate rule %q", name)
+	}
+}
+
+func isNumber(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func hasLength(k reflect.Kind) bool {
+	switch k {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		return true
+	}
+	return false
+}
+
+func number(v reflect.Value) float64 {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	default:
+		return float64(v.Uint())
+	}
+}
+
+// 字符串以字符计算长度
+func length(v reflect.Value) int {
+	if v.Kind() == reflect.String {
+		return utf8.RuneCountInString(v.String())
+	}
+	return v.Len()
+}
+
+func format(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64)
+	default:
+		return strconv.FormatUint(v.Uint(), 10)
+	}
+}
+
+// 参数的验证器
+type validator struct {
+	param  int
+	errors []*webview.FieldError
+}
+
+// 验证第 param 个参数 v
+func validate(param int, v reflect.Value) []*webview.FieldError {
+	vv := &validator{param: param}
+	vv.value(v, "")
+	return vv.errors
+}
+
+func (vv *validator) add(path, rule, msg string) {
+	vv.errors = append(vv.errors, &webview.FieldError{Param: vv.param, Field: path, Rule: rule, Message: msg})
+}
+
+func (vv *validator) value(v reflect.Value, path string) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	count := len(vv.errors)
+	switch v.Kind() {
+	case reflect.Struct:
+		vv.structValue(v, path)
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		for i := 0; i < v.Len(); i++ {
+			vv.value(v.Index(i), path+"["+strconv.Itoa(i)+"]")
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			vv.value(iter.Value(), join(path, fmt.Sprint(iter.Key().Interface())))
+		}
+	}
+
+	if len(vv.errors) == count { // 只有标签验证通过之后才调用 Validate
+		vv.validator(v, path)
+	}
+}
+
+func (vv *validator) structValue(v reflect.Value, path string) {
+	for _, f := range getStructRules(v.Type()).fields {
+		fv := v.Field(f.index)
+		p := path
+		if !f.embedded {
+			p = join(path, f.name)
+		}
+
+		if f.required && isEmpty(fv) {
+			vv.add(p, "required", "is required")
+			continue
+		}
+
+		ev := fv
+		for ev.Kind() == reflect.Ptr && !ev.IsNil() {
+			ev = ev.Elem()
+		}
+		if ev.Kind() == reflect.Ptr || (f.omitEmpty && isOmitted(ev)) {
+			continue
+		}
+
+		valid := true
+		for _, r := range f.rules {
+			if !r.check(ev) {
+				vv.add(p, r.name, r.message)
+				valid = false
+				break
+			}
+		}
+		if valid {
+			vv.value(fv, p)
+		}
+	}
+}
+
+// 调用 v 的 Validate 方法
+func (vv *validator) validator(v reflect.Value, path string) {
+	if !v.CanInterface() {
+		return
+	}
+	if !v.Type().Implements(validatorType) {
+		if !v.CanAddr() || !reflect.PtrTo(v.Type()).Implements(validatorType) {
+			return
+		}
+		v = v.Addr()
+	}
+
+	err := v.Interface().(webview.Validator).Validate()
+	if err == nil {
+		return
+	}
+
+	var ve *webview.ValidationError
+	if !errors.As(err, &ve) {
+		vv.add(path, "validate", err.Error())
+		return
+	}
+	for _, f := range ve.Fields {
+		vv.add(join(path, f.Field), f.Rule, f.Message)
+	}
+}
+
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+		return v.IsNil()
+	default:
+		return v.IsZero()
+	}
+}
+
+// 与 encoding/json 的 omitempty 相同，长度为 0 的值也被视为空值。
+func isOmitted(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		return v.Len() == 0
+	default:
+		return isEmpty(v)
+	}
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	if name == "" {
+		return path
+	}
+	return path + "." + name
+}
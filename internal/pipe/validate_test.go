@@ -0,0 +1,146 @@
+// SPDX-License-Identifier: MIT
+
+package pipe
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/issue9/assert/v3"
+
+	"github.com/issue9/webview"
+)
+
+type address struct {
+	City string `json:"city" validate:"required"`
+	Zip  string `json:"zip" validate:"omitempty,len=6,regexp=^[0-9,]+$"`
+}
+
+type base struct {
+	ID int `json:"id" validate:"min=1"`
+}
+
+type account struct {
+	base
+	Name     string            `json:"name" validate:"required,min=2,max=4"`
+	Age      *int              `json:"age" validate:"max=150"`
+	Role     string            `json:"role" validate:"oneof=admin user"`
+	Level    int               `json:"level" validate:"oneof=1 2 3"`
+	Tags     []string          `json:"tags" validate:"max=2"`
+	Address  *address          `json:"address" validate:"required"`
+	Others   []*address        `json:"others"`
+	Meta     map[string]string `json:"meta" validate:"omitempty,len=1"`
+	Password string            `json:"-" validate:"required"`
+}
+
+func (a *account) Validate() error {
+	if a.Role == "admin" && a.Level != 3 {
+		return &webview.ValidationError{Fields: []*webview.FieldError{{Param: 5, Field: "level", Rule: "admin", Message: "admin level must be 3"}}}
+	}
+	return nil
+}
+
+type positive int
+
+func (p positive) Validate() error {
+	if p <= 0 {
+		return errors.New("must be positive")
+	}
+	return nil
+}
+
+func TestCheckRules(t *testing.T) {
+	a := assert.New(t, false)
+
+	a.NotError(checkRules(reflect.TypeOf(&account{}))).
+		NotError(checkRules(reflect.TypeOf(map[string][]account{}))).
+		NotError(checkRules(reflect.TypeOf(5)))
+
+	type node struct {
+		Next *node `validate:"required"`
+	}
+	a.NotError(checkRules(reflect.TypeOf(node{})))
+
+	for _, v := range []interface{}{
+		struct {
+			V int `validate:"unknown"`
+		}{},
+		struct {
+			V int `validate:"len=5"`
+		}{},
+		struct {
+			V bool `validate:"min=1"`
+		}{},
+		struct {
+			V string `validate:"max=x"`
+		}{},
+		struct {
+			V string `validate:"oneof="`
+		}{},
+		struct {
+			V int `validate:"regexp=^\\d+$"`
+		}{},
+		struct {
+			V string `validate:"regexp=["`
+		}{},
+		[]struct {
+			V []bool `validate:"oneof=true"`
+		}{},
+	} {
+		a.Error(checkRules(reflect.TypeOf(v)), "%T", v)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	a := assert.New(t, false)
+
+	age := 200
+	v := &account{
+		base:    base{ID: 0},
+		Name:    "n",
+		Age:     &age,
+		Role:    "guest",
+		Level:   4,
+		Tags:    []string{"1", "2", "3"},
+		Others:  []*address{{City: "c", Zip: "12345"}, nil, {}},
+		Meta:    map[string]string{},
+		Address: nil,
+	}
+	a.Equal(validate(1, reflect.ValueOf(v)), []*webview.FieldError{
+		{Param: 1, Field: "id", Rule: "min", Message: "must be at least 1"},
+		{Param: 1, Field: "name", Rule: "min", Message: "length must be at least 2"},
+		{Param: 1, Field: "age", Rule: "max", Message: "must be at most 150"},
+		{Param: 1, Field: "role", Rule: "oneof", Message: "must be one of admin, user"},
+		{Param: 1, Field: "level", Rule: "oneof", Message: "must be one of 1, 2, 3"},
+		{Param: 1, Field: "tags", Rule: "max", Message: "length must be at most 2"},
+		{Param: 1, Field: "address", Rule: "required", Message: "is required"},
+		{Param: 1, Field: "others[0].zip", Rule: "len", Message: "length must be 6"},
+		{Param: 1, Field: "others[2].city", Rule: "required", Message: "is required"},
+	})
+
+	// 标签验证通过之后才调用 Validate
+	v = &account{
+		base:    base{ID: 1},
+		Name:    "名字名字",
+		Role:    "admin",
+		Level:   2,
+		Address: &address{City: "c", Zip: "1,2,34"},
+		Meta:    map[string]string{"k": "v"},
+	}
+	a.Equal(validate(0, reflect.ValueOf(v).Elem()), []*webview.FieldError{
+		{Param: 0, Field: "level", Rule: "admin", Message: "admin level must be 3"},
+	})
+
+	v.Level = 3
+	a.Empty(validate(0, reflect.ValueOf(v)))
+
+	// 嵌套的 Validator
+	a.Equal(validate(2, reflect.ValueOf(map[string][]positive{"k": {1, 0}})), []*webview.FieldError{
+		{Param: 2, Field: "k[1]", Rule: "validate", Message: "must be positive"},
+	})
+	a.Equal(validate(0, reflect.ValueOf(positive(0))), []*webview.FieldError{
+		{Param: 0, Rule: "validate", Message: "must be positive"},
+	})
+	a.Empty(validate(0, reflect.ValueOf(5)))
+}
@@ -11,3 +11,9 @@ const (
 	// 窗口的默认标题
 	Title = "webview"
 )
+
+// Workers 同时执行绑定方法的默认最大数量
+const Workers = 16
+
+// Queue 等待执行的绑定方法的默认最大数量
+const Queue = 256
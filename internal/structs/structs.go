@@ -0,0 +1,167 @@
+// SPDX-License-Identifier: MIT
+
+// Package structs 按 encoding/json 的规则获取结构体的字段
+//
+// 由 codec 和 dts 共用，保证编码的结果与生成的 TypeScript 声明一致。
+package structs
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Field 结构体的字段
+type Field struct {
+	Name      string // json 中的名称
+	Index     []int  // 字段的索引，可用于 reflect.Type.FieldByIndex。
+	Type      reflect.Type
+	OmitEmpty bool // json 标签的 omitempty 选项
+	String    bool // json 标签的 string 选项
+}
+
+var fieldsCache = &sync.Map{}
+
+// Fields 按 encoding/json 的规则获取结构体 t 的字段
+//
+// 嵌入的结构体，其字段会被提升。同名的字段以层级浅的为准，
+// 层级相同时有 json 标签的优先，如果依然无法区分，则这些字段都会被忽略。
+// 非导出的嵌入结构体指针会被忽略。返回的字段按声明的顺序排列。
+func Fields(t reflect.Type) []*Field {
+	if fs, found := fieldsCache.Load(t); found {
+		return fs.([]*Field)
+	}
+
+	type item struct {
+		*Field
+		tagged bool // 名称是否来自 json 标签
+	}
+
+	type embed struct {
+		typ   reflect.Type
+		index []int
+	}
+
+	// 与 encoding/json 相同，按层级广度优先遍历，
+	// count 记录类型在当前层级出现的次数，出现多次的类型，其字段在同一层级中无法区分。
+	items := []*item{}
+	current, next := []embed{}, []embed{{typ: t}}
+	count, nextCount := map[reflect.Type]int{}, map[reflect.Type]int{t: 1}
+	visited := map[reflect.Type]bool{}
+	for len(next) > 0 {
+		current, next = next, current[:0]
+		count, nextCount = nextCount, map[reflect.Type]int{}
+
+		for _, e := range current {
+			if visited[e.typ] { // 层级更浅的同类型已经处理过
+				continue
+			}
+			visited[e.typ] = true
+
+			for i := 0; i < e.typ.NumField(); i++ {
+				f := e.typ.Field(i)
+
+				tag := f.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				name, opts := tag, ""
+				if index := strings.IndexByte(tag, ','); index >= 0 {
+					name, opts = tag[:index], tag[index+1:]
+				}
+
+				index := make([]int, len(e.index)+1)
+				copy(index, e.index)
+				index[len(e.index)] = i
+
+				if f.Anonymous && name == "" { // 嵌入的结构体，其字段被提升至当前结构体。
+					ft := f.Type
+					if ft.Kind() == reflect.Ptr {
+						ft = ft.Elem()
+					}
+					if ft.Kind() == reflect.Struct {
+						if f.IsExported() || f.Type.Kind() != reflect.Ptr {
+							if nextCount[ft]++; nextCount[ft] == 1 {
+								next = append(next, embed{typ: ft, index: index})
+							}
+						}
+						continue
+					}
+				}
+				if !f.IsExported() {
+					continue
+				}
+
+				it := &item{
+					Field: &Field{
+						Name:      name,
+						Index:     index,
+						Type:      f.Type,
+						OmitEmpty: hasOption(opts, "omitempty"),
+						String:    hasOption(opts, "string"),
+					},
+					tagged: name != "",
+				}
+				if !it.tagged {
+					it.Name = f.Name
+				}
+				items = append(items, it)
+				if count[e.typ] > 1 { // 同一层级中有多个相同的类型，添加重复项以便之后被忽略。
+					items = append(items, it)
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		x, y := items[i], items[j]
+		switch {
+		case x.Name != y.Name:
+			return x.Name < y.Name
+		case len(x.Index) != len(y.Index):
+			return len(x.Index) < len(y.Index)
+		case x.tagged != y.tagged:
+			return x.tagged
+		default:
+			return lessIndex(x.Index, y.Index)
+		}
+	})
+
+	// 同名的字段中，只有层级最浅且有标签的字段是唯一的，才会被保留。
+	fs := make([]*Field, 0, len(items))
+	for i := 0; i < len(items); {
+		j := i + 1
+		for j < len(items) && items[j].Name == items[i].Name {
+			j++
+		}
+		if dominant := items[i:j]; len(dominant) == 1 ||
+			len(dominant[0].Index) != len(dominant[1].Index) || dominant[0].tagged != dominant[1].tagged {
+			fs = append(fs, dominant[0].Field)
+		}
+		i = j
+	}
+	sort.Slice(fs, func(i, j int) bool { return lessIndex(fs[i].Index, fs[j].Index) })
+
+	fieldsCache.Store(t, fs)
+	return fs
+}
+
+// 按字段的声明顺序排序
+func lessIndex(i, j []int) bool {
+	for k := 0; k < len(i) && k < len(j); k++ {
+		if i[k] != j[k] {
+			return i[k] < j[k]
+		}
+	}
+	return len(i) < len(j)
+}
+
+func hasOption(opts, opt string) bool {
+	for _, o := range strings.Split(opts, ",") {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
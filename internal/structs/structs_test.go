@@ -0,0 +1,129 @@
+// SPDX-License-Identifier: MIT
+
+package structs
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/issue9/assert/v3"
+)
+
+type Base struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type embedded struct {
+	Embedded string
+}
+
+type object struct {
+	*Base
+	embedded
+	Name     string `json:"name,omitempty"`
+	Ignored  int    `json:"-"`
+	internal int
+	Tags     []string
+	Age      int `json:"age,string"`
+}
+
+func TestFields(t *testing.T) {
+	a := assert.New(t, false)
+
+	fs := Fields(reflect.TypeOf(object{}))
+	a.Length(fs, 5).
+		Equal(fs[0], &Field{Name: "id", Index: []int{0, 0}, Type: reflect.TypeOf(0)}).
+		Equal(fs[1], &Field{Name: "Embedded", Index: []int{1, 0}, Type: reflect.TypeOf("")}).
+		Equal(fs[2], &Field{Name: "name", Index: []int{2}, Type: reflect.TypeOf(""), OmitEmpty: true}).
+		Equal(fs[3], &Field{Name: "Tags", Index: []int{5}, Type: reflect.TypeOf([]string{})}).
+		Equal(fs[4], &Field{Name: "age", Index: []int{6}, Type: reflect.TypeOf(0), String: true})
+
+	// 缓存
+	a.Equal(Fields(reflect.TypeOf(object{})), fs)
+}
+
+type (
+	A struct{ X int }
+	B struct{ X int }
+	C struct {
+		A
+		B
+	}
+
+	Tagged struct {
+		X int `json:"X"`
+	}
+	Tagged2 struct {
+		Y int `json:"X"`
+	}
+	taggedWins struct {
+		A
+		Tagged
+	}
+
+	shallow struct {
+		C
+		X string
+	}
+
+	W1    struct{ A }
+	W2    struct{ A }
+	twice struct { // A 在同一层级出现两次
+		W1
+		W2
+		Y int
+	}
+
+	Recursive struct {
+		*Recursive
+		Name string
+	}
+)
+
+func TestFields_dominant(t *testing.T) {
+	a := assert.New(t, false)
+
+	// 同层级的字段都有标签，go vet 不允许直接声明此类型。
+	ambiguousTags := reflect.StructOf([]reflect.StructField{
+		{Name: "Tagged", Type: reflect.TypeOf(Tagged{}), Anonymous: true},
+		{Name: "Tagged2", Type: reflect.TypeOf(Tagged2{}), Anonymous: true},
+		{Name: "Z", Type: reflect.TypeOf(0)},
+	})
+
+	data := []struct {
+		v     interface{}
+		names []string
+	}{
+		{v: C{}, names: []string{}},                                              // 同层级的同名字段都被忽略
+		{v: taggedWins{}, names: []string{"X"}},                                  // 有标签的优先
+		{v: shallow{}, names: []string{"X"}},                                     // 层级浅的优先
+		{v: twice{}, names: []string{"Y"}},                                       // 同一类型在同层级出现多次
+		{v: reflect.New(ambiguousTags).Elem().Interface(), names: []string{"Z"}}, // 同层级都有标签
+		{v: Recursive{}, names: []string{"Name"}},                                // 嵌入自身
+	}
+
+	for _, item := range data {
+		fs := Fields(reflect.TypeOf(item.v))
+		names := make([]string, 0, len(fs))
+		for _, f := range fs {
+			names = append(names, f.Name)
+		}
+		a.Equal(names, item.names, reflect.TypeOf(item.v))
+
+		// 与 encoding/json 的结果相同
+		data, err := json.Marshal(item.v)
+		a.NotError(err)
+		m := map[string]interface{}{}
+		a.NotError(json.Unmarshal(data, &m))
+		a.Length(m, len(names), reflect.TypeOf(item.v))
+		for _, name := range names {
+			_, found := m[name]
+			a.True(found, name)
+		}
+	}
+
+	f := Fields(reflect.TypeOf(shallow{}))[0]
+	a.Equal(f.Index, []int{1}).Equal(f.Type, reflect.TypeOf(""))
+}
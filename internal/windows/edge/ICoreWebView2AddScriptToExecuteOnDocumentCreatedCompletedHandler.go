@@ -0,0 +1,50 @@
+//go:build windows
+
+package edge
+
+type _ICoreWebView2AddScriptToExecuteOnDocumentCreatedCompletedHandlerVtbl struct {
+	_IUnknownVtbl
+	Invoke ComProc
+}
+
+type iCoreWebView2AddScriptToExecuteOnDocumentCreatedCompletedHandler struct {
+	vtbl *_ICoreWebView2AddScriptToExecuteOnDocumentCreatedCompletedHandlerVtbl
+	impl _ICoreWebView2AddScriptToExecuteOnDocumentCreatedCompletedHandlerImpl
+}
+
+func _ICoreWebView2AddScriptToExecuteOnDocumentCreatedCompletedHandlerIUnknownQueryInterface(this *iCoreWebView2AddScriptToExecuteOnDocumentCreatedCompletedHandler, refiid, object uintptr) uintptr {
+	return this.impl.QueryInterface(refiid, object)
+}
+
+func _ICoreWebView2AddScriptToExecuteOnDocumentCreatedCompletedHandlerIUnknownAddRef(this *iCoreWebView2AddScriptToExecuteOnDocumentCreatedCompletedHandler) uintptr {
+	return this.impl.AddRef()
+}
+
+func _ICoreWebView2AddScriptToExecuteOnDocumentCreatedCompletedHandlerIUnknownRelease(this *iCoreWebView2AddScriptToExecuteOnDocumentCreatedCompletedHandler) uintptr {
+	return this.impl.Release()
+}
+
+func _ICoreWebView2AddScriptToExecuteOnDocumentCreatedCompletedHandlerInvoke(this *iCoreWebView2AddScriptToExecuteOnDocumentCreatedCompletedHandler, errorCode uintptr, id *uint16) uintptr {
+	return this.impl.AddScriptToExecuteOnDocumentCreatedCompleted(errorCode, id)
+}
+
+type _ICoreWebView2AddScriptToExecuteOnDocumentCreatedCompletedHandlerImpl interface {
+	_IUnknownImpl
+	AddScriptToExecuteOnDocumentCreatedCompleted(errorCode uintptr, id *uint16) uintptr
+}
+
+var _ICoreWebView2AddScriptToExecuteOnDocumentCreatedCompletedHandlerFn = _ICoreWebView2AddScriptToExecuteOnDocumentCreatedCompletedHandlerVtbl{
+	_IUnknownVtbl{
+		NewComProc(_ICoreWebView2AddScriptToExecuteOnDocumentCreatedCompletedHandlerIUnknownQueryInterface),
+		NewComProc(_ICoreWebView2AddScriptToExecuteOnDocumentCreatedCompletedHandlerIUnknownAddRef),
+		NewComProc(_ICoreWebView2AddScriptToExecuteOnDocumentCreatedCompletedHandlerIUnknownRelease),
+	},
+	NewComProc(_ICoreWebView2AddScriptToExecuteOnDocumentCreatedCompletedHandlerInvoke),
+}
+
+func newICoreWebView2AddScriptToExecuteOnDocumentCreatedCompletedHandler(impl _ICoreWebView2AddScriptToExecuteOnDocumentCreatedCompletedHandlerImpl) *iCoreWebView2AddScriptToExecuteOnDocumentCreatedCompletedHandler {
+	return &iCoreWebView2AddScriptToExecuteOnDocumentCreatedCompletedHandler{
+		vtbl: &_ICoreWebView2AddScriptToExecuteOnDocumentCreatedCompletedHandlerFn,
+		impl: impl,
+	}
+}
@@ -30,6 +30,7 @@ type Chromium struct {
 	webResourceRequested  *iCoreWebView2WebResourceRequestedEventHandler
 	acceleratorKeyPressed *ICoreWebView2AcceleratorKeyPressedEventHandler
 	navigationCompleted   *ICoreWebView2NavigationCompletedEventHandler
+	scriptsAdding         map[*scriptAdded]struct{} // 等待 AddScript 返回结果的对象，防止被回收。
 
 	environment *ICoreWebView2Environment
 
@@ -41,7 +42,7 @@ type Chromium struct {
 	globalPermission *CoreWebView2PermissionState
 
 	// Callbacks
-	MessageCallback              func(string)
+	MessageCallback              func(message, source string) // source 为发送消息的页面地址
 	WebResourceRequestedCallback func(request *ICoreWebView2WebResourceRequest, args *ICoreWebView2WebResourceRequestedEventArgs)
 	NavigationCompletedCallback  func(sender *ICoreWebView2, args *ICoreWebView2NavigationCompletedEventArgs)
 	AcceleratorKeyCallback       func(uint) bool
@@ -68,6 +69,7 @@ func NewChromium(errlog*log.Logger) *Chromium {
 	e.acceleratorKeyPressed = newICoreWebView2AcceleratorKeyPressedEventHandler(e)
 	e.navigationCompleted = newICoreWebView2NavigationCompletedEventHandler(e)
 	e.permissions = make(map[CoreWebView2PermissionKind]CoreWebView2PermissionState)
+	e.scriptsAdding = make(map[*scriptAdded]struct{})
 
 	return e
 }
@@ -131,6 +133,52 @@ func (e *Chromium) Init(script string) {
 	)
 }
 
+// AddScript 与 Init 相同，但是可以通过 RemoveScript 删除注入的代码
+//
+// 注入代码的 ID 由 done 异步返回。
+func (e *Chromium) AddScript(script string, done func(id string)) {
+	s := &scriptAdded{e: e, done: done}
+	s.handler = newICoreWebView2AddScriptToExecuteOnDocumentCreatedCompletedHandler(s)
+	e.scriptsAdding[s] = struct{}{}
+
+	_, _, _ = e.webview.vtbl.AddScriptToExecuteOnDocumentCreated.Call(
+		uintptr(unsafe.Pointer(e.webview)),
+		uintptr(unsafe.Pointer(windows.StringToUTF16Ptr(script))),
+		uintptr(unsafe.Pointer(s.handler)),
+	)
+}
+
+// RemoveScript 删除由 AddScript 注入的代码
+func (e *Chromium) RemoveScript(id string) {
+	_, _, _ = e.webview.vtbl.RemoveScriptToExecuteOnDocumentCreated.Call(
+		uintptr(unsafe.Pointer(e.webview)),
+		uintptr(unsafe.Pointer(windows.StringToUTF16Ptr(id))),
+	)
+}
+
+// 接收 AddScript 的结果
+type scriptAdded struct {
+	e       *Chromium
+	handler *iCoreWebView2AddScriptToExecuteOnDocumentCreatedCompletedHandler
+	done    func(id string)
+}
+
+func (s *scriptAdded) QueryInterface(_, _ uintptr) uintptr { return 0 }
+
+func (s *scriptAdded) AddRef() uintptr { return 1 }
+
+func (s *scriptAdded) Release() uintptr { return 1 }
+
+func (s *scriptAdded) AddScriptToExecuteOnDocumentCreatedCompleted(res uintptr, id *uint16) uintptr {
+	delete(s.e.scriptsAdding, s)
+	if int64(res) < 0 {
+		s.e.errlog.Printf("Adding script failed with %08x", res)
+		return 0
+	}
+	s.done(windows.UTF16PtrToString(id))
+	return 0
+}
+
 func (e *Chromium) Eval(script string) {
 	_script, err := windows.UTF16PtrFromString(script)
 	if err != nil {
@@ -235,9 +283,15 @@ func (e *Chromium) MessageReceived(sender *ICoreWebView2, args *iCoreWebView2Web
 		uintptr(unsafe.Pointer(args)),
 		uintptr(unsafe.Pointer(&message)),
 	)
+	var source *uint16
+	_, _, _ = args.vtbl.GetSource.Call(
+		uintptr(unsafe.Pointer(args)),
+		uintptr(unsafe.Pointer(&source)),
+	)
 	if e.MessageCallback != nil {
-		e.MessageCallback(windows.UTF16PtrToString(message))
+		e.MessageCallback(windows.UTF16PtrToString(message), windows.UTF16PtrToString(source))
 	}
+	windows.CoTaskMemFree(unsafe.Pointer(source))
 	_, _, _ = sender.vtbl.PostWebMessageAsString.Call(
 		uintptr(unsafe.Pointer(sender)),
 		uintptr(unsafe.Pointer(message)),
@@ -35,6 +35,7 @@ var (
 	user32DispatchMessageW   = user32.NewProc("DispatchMessageW")
 	user32PostQuitMessage    = user32.NewProc("PostQuitMessage")
 	user32PostThreadMessageW = user32.NewProc("PostThreadMessageW")
+	user32PostMessageW       = user32.NewProc("PostMessageW")
 	user32IsDialogMessage    = user32.NewProc("IsDialogMessage")
 	user32GetClientRect      = user32.NewProc("GetClientRect")
 	user32GetAncestor        = user32.NewProc("GetAncestor")
@@ -59,6 +60,10 @@ func PostThreadMessage(thread uintptr, msg uint, w, l uintptr) {
 	user32PostThreadMessageW.Call(thread, uintptr(msg), w, l)
 }
 
+func PostMessage(h uintptr, msg uint, w, l uintptr) {
+	user32PostMessageW.Call(h, uintptr(msg), w, l)
+}
+
 func PostQuitMessage(code int) { user32PostQuitMessage.Call(uintptr(code)) }
 
 func DispatchMessage(m *Msg) { user32DispatchMessageW.Call(uintptr(unsafe.Pointer(m))) }
@@ -12,6 +12,8 @@ package darwin
 */
 import "C"
 import (
+	"encoding/json"
+	"io"
 	"runtime"
 	"unsafe"
 
@@ -23,16 +25,21 @@ func init() {
 	runtime.LockOSThread()
 }
 
-var binder *pipe.Binder
-
 type desktop struct {
 	title    string
 	position webview.Point
 	size     webview.Size
 	app      *C.App
+	binder   *pipe.Binder
+	scripts  []string // 通过 OnLoad 注入的代码
 }
 
-func New(o *Options) webview.Desktop {
+// New 在默认的 webview.Application 中创建窗口
+//
+// 与 NewApplication().NewWindow(o) 相同。
+func New(o *Options) webview.Desktop { return defaultApp.newWindow(o) }
+
+func (a *application) newWindow(o *Options) *desktop {
 	o = sanitizeOptions(o)
 
 	t := C.CString(o.Title)
@@ -46,7 +53,12 @@ func New(o *Options) webview.Desktop {
 		size:     o.Size,
 		app:      wv,
 	}
-	binder = pipe.NewBinder(d, d.eval, func() { C.dispatch() }, o.Error)
+	bo := o.binderOptions()
+	bo.Inject = d.inject
+	d.binder = pipe.NewBinder(d, d.eval, func() { C.dispatch(d.app.id) }, bo)
+	d.app.id = C.int(d.binder.WindowID())
+
+	a.add(d)
 
 	return d
 }
@@ -70,21 +82,64 @@ func (d *desktop) Load(url string) {
 }
 
 func (d *desktop) OnLoad(js string) {
+	d.scripts = append(d.scripts, js)
+	d.addUserScript(js)
+}
+
+// 替换 Binder 注入的代码
+//
+// WKUserContentController 只能删除所有的代码，需要重新注入 OnLoad 中的代码。
+func (d *desktop) inject(js string) {
+	C.remove_user_scripts(d.app)
+	for _, s := range d.scripts {
+		d.addUserScript(s)
+	}
+	if js != "" {
+		d.addUserScript(js)
+	}
+}
+
+func (d *desktop) addUserScript(js string) {
 	t := C.CString(js)
 	defer C.free(unsafe.Pointer(t))
 	C.add_user_script(d.app, t)
 }
 
 func (d *desktop) Bind(name string, f interface{}) error {
-	return binder.Bind(name, f)
+	return d.binder.Bind(name, f)
+}
+
+func (d *desktop) BindObject(namespace string, obj interface{}, mapper webview.NameMapper) error {
+	return d.binder.BindObject(namespace, obj, mapper)
 }
 
-func (d *desktop) Run() {
-	C.run()
+func (d *desktop) Unbind(name string) { d.binder.Unbind(name) }
+
+func (d *desktop) TypeScript(w io.Writer) error { return d.binder.TypeScript(w) }
+
+func (d *desktop) Emit(event string, payload interface{}) error {
+	return d.binder.Emit(event, payload)
 }
 
+func (d *desktop) On(event string, f func(json.RawMessage)) { d.binder.On(event, f) }
+
+func (d *desktop) Off(event string) { d.binder.Off(event) }
+
+func (d *desktop) Dispatch(f func()) { d.binder.Dispatch(f) }
+
+func (d *desktop) DispatchSync(f func()) error {
+	if C.is_main_thread() {
+		f()
+		return nil
+	}
+	return d.binder.DispatchSync(f)
+}
+
+func (d *desktop) Run() { defaultApp.Run() }
+
 func (d *desktop) Close() {
-	C.terminate()
+	d.binder.Close()
+	C.close_window(d.app)
 }
 
 func (d *desktop) Title() string { return d.title }
@@ -118,11 +173,17 @@ func (d *desktop) SetSize(s webview.Size, h webview.Hint) {
 }
 
 //export dispatchCallback
-func dispatchCallback() {
-	binder.DispatchCallback()
+func dispatchCallback(id C.int) {
+	if d, found := defaultApp.desktop(id); found {
+		d.binder.DispatchCallback()
+	}
 }
 
+// url 为发送消息的页面地址，origin 为发送消息的框架的 origin。
+//
 //export messageCallback
-func messageCallback(msg *C.char) {
-	binder.MessageHandler(C.GoString(msg))
+func messageCallback(id C.int, msg, url, origin *C.char) {
+	if d, found := defaultApp.desktop(id); found {
+		d.binder.HandleMessage(C.GoString(msg), pipe.Source{URL: C.GoString(url), Origin: C.GoString(origin)})
+	}
 }
@@ -8,12 +8,18 @@
 typedef struct {
     NSWindow* win;
     WKWebView* wv;
+    int id; // 窗口的 ID，回调函数根据该值查找对应的窗口。
 } App;
 
 @interface AppDelegate: NSObject<NSApplicationDelegate>
 @end
 
+@interface AppWindowDelegate: NSObject<NSWindowDelegate>
+@property (assign) App* app;
+@end
+
 @interface AppScriptMessageHandler : NSObject <WKScriptMessageHandler>
+@property (assign) App* app;
 @end
 
 App* create_cocoa(bool debug, CGFloat x, CGFloat y, CGFloat w, CGFloat h, NSWindowStyleMask style, const char* title);
@@ -22,6 +28,8 @@ void set_title(App* wv, const char* title);
 
 void add_user_script(App* wv, const char* js);
 
+void remove_user_scripts(App* wv);
+
 void eval(App* wv, const char* js);
 
 void load(App* wv, const char* url);
@@ -36,8 +44,12 @@ void set_min_size(App* wv, CGFloat w, CGFloat h);
 
 void set_max_size(App* wv, CGFloat w, CGFloat h);
 
-void dispatch();
+bool is_main_thread();
+
+void dispatch(int id);
+
+void close_window(App* wv);
 
-void terminate();
+void stop();
 
 void run();
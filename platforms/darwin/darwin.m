@@ -9,14 +9,32 @@ void _add_user_script(WKWebViewConfiguration* config, NSString* js) {
     [config.userContentController addUserScript:script];
 }
 
+// 所有页面都需要的代码，前端通过 window.external.invoke 向 Go 发送消息。
+static NSString* external_js = @"window.external = {\
+    invoke: function(s) {\
+        window.webkit.messageHandlers.external.postMessage(s);\
+    },\
+};";
+
 @implementation AppDelegate
 
+// Cmd+Q 等方式的退出交由 Application.Quit 处理，以便关闭所有窗口并执行 OnQuit 注册的函数。
 - (NSApplicationTerminateReply)applicationShouldTerminate:(NSApplication *)sender {
-    return NSTerminateNow;
+    quitCallback();
+    return NSTerminateCancel;
 }
 
+// 由 Go 在最后一个窗口关闭之后退出消息循环，以便 run 可以正常返回。
 - (BOOL)applicationShouldTerminateAfterLastWindowClosed:(NSApplication *)sender {
-    return YES;
+    return NO;
+}
+
+@end
+
+@implementation AppWindowDelegate
+
+- (void)windowWillClose:(NSNotification *)notification {
+    closedCallback(self.app->id);
 }
 
 @end
@@ -25,7 +43,16 @@ void _add_user_script(WKWebViewConfiguration* config, NSString* js) {
 
 - (void)userContentController:(WKUserContentController *)userContentController
       didReceiveScriptMessage:(WKScriptMessage *)message {
-    messageCallback((char *) [message.body description].UTF8String);
+    WKSecurityOrigin* o = message.frameInfo.securityOrigin;
+    NSString* origin = @"null"; // 与 location.origin 相同，about:blank 等没有 origin 的页面为 null。
+    if (o.protocol.length > 0) {
+        origin = o.port == 0
+            ? [NSString stringWithFormat:@"%@://%@", o.protocol, o.host]
+            : [NSString stringWithFormat:@"%@://%@:%ld", o.protocol, o.host, (long)o.port];
+    }
+    messageCallback(self.app->id, (char *) [message.body description].UTF8String,
+        (char *) message.webView.URL.absoluteString.UTF8String,
+        (char *) origin.UTF8String);
 }
 
 @end
@@ -35,27 +62,33 @@ void _add_user_script(WKWebViewConfiguration* config, NSString* js) {
 // title 为窗口标题
 App* create_cocoa(bool debug, CGFloat x, CGFloat y, CGFloat w, CGFloat h, NSWindowStyleMask s, const char* title) {
     [NSApplication sharedApplication];
-    NSApp.delegate = [AppDelegate alloc];
-    
+    if (NSApp.delegate == nil) { // 多个窗口共用同一个 NSApp
+        NSApp.delegate = [AppDelegate alloc];
+    }
+
+    // App 在窗口关闭之后也不会释放，Go 中的对象依然引用着它。
+    App* ret = malloc(sizeof(App));
+    ret->id = 0;
+
     CGRect rect = CGRectMake(x, y, w, h);
     NSWindow* win = [[NSWindow alloc]initWithContentRect:rect styleMask:s backing:NSBackingStoreBuffered defer:NO];
+    win.releasedWhenClosed = NO;
+    AppWindowDelegate* delegate = [[AppWindowDelegate alloc] init];
+    delegate.app = ret;
+    win.delegate = delegate;
     [win center];
     [win makeKeyAndOrderFront:nil];
     
     WKWebViewConfiguration* config = [[WKWebViewConfiguration alloc] init];
     [config.preferences setValue:[NSNumber numberWithBool:debug] forKey:@"developerExtrasEnabled"];
-    _add_user_script(config, @"window.external = {\
-        invoke: function(s) {\
-            window.webkit.messageHandlers.external.postMessage(s);\
-        },\
-    };");
-    id<WKScriptMessageHandler> messageHandler = [[AppScriptMessageHandler alloc] init];
+    _add_user_script(config, external_js);
+    AppScriptMessageHandler* messageHandler = [[AppScriptMessageHandler alloc] init];
+    messageHandler.app = ret;
     [config.userContentController addScriptMessageHandler:messageHandler name:@"external"];
     
     WKWebView* wv = [[WKWebView alloc] initWithFrame:rect configuration: config];
     win.contentView=wv;
     
-    App* ret = malloc(sizeof(App));
     ret->wv = wv;
     ret->win = win;
     return ret;
@@ -71,6 +104,12 @@ void add_user_script(App* wv, const char* js) {
     _add_user_script(wv->wv.configuration, str);
 }
 
+// 删除所有通过 add_user_script 注入的代码
+void remove_user_scripts(App* wv) {
+    [wv->wv.configuration.userContentController removeAllUserScripts];
+    _add_user_script(wv->wv.configuration, external_js);
+}
+
 void eval(App* wv, const char* js) {
     NSString* str = [NSString stringWithUTF8String:js];
     [wv->wv evaluateJavaScript:str completionHandler:nil];
@@ -106,16 +145,35 @@ void set_max_size(App* wv, CGFloat w, CGFloat h) {
     wv->win.maxSize = CGSizeMake(w, h);
 }
 
-void dispatch_cb(void* f) {
-    dispatchCallback();
+bool is_main_thread() {
+    return [NSThread isMainThread];
 }
 
-void dispatch() {
-    dispatch_async_f(dispatch_get_main_queue(), nil, (dispatch_function_t)dispatch_cb);
+// id 为窗口的 ID
+void dispatch_cb(void* id) {
+    dispatchCallback((int)(intptr_t)id);
 }
 
-void terminate() {
-    [NSApp terminate:nil];
+void dispatch(int id) {
+    dispatch_async_f(dispatch_get_main_queue(), (void*)(intptr_t)id, (dispatch_function_t)dispatch_cb);
+}
+
+void close_window(App* wv) {
+    dispatch_async(dispatch_get_main_queue(), ^{
+        [wv->win close];
+    });
+}
+
+void stop() {
+    dispatch_async(dispatch_get_main_queue(), ^{
+        [NSApp stop:nil];
+
+        // stop 在处理完下一个事件之后才生效，发送一个空事件以便 run 立即返回。
+        NSEvent* e = [NSEvent otherEventWithType:NSEventTypeApplicationDefined
+            location:NSZeroPoint modifierFlags:0 timestamp:0 windowNumber:0
+            context:nil subtype:0 data1:0 data2:0];
+        [NSApp postEvent:e atStart:YES];
+    });
 }
 
 void run() {
@@ -0,0 +1,72 @@
+// SPDX-License-Identifier: MIT
+
+//go:build linux || openbsd || freebsd || netbsd
+
+package gtk
+
+/*
+#include "gtk.h"
+*/
+import "C"
+import (
+	"sync"
+
+	"github.com/issue9/webview"
+	"github.com/issue9/webview/internal/lifecycle"
+)
+
+// 整个进程只有一个 gtk_main，所以也只有一个 application。
+var defaultApp = &application{
+	Lifecycle: lifecycle.New(func() { C.quit() }),
+	desktops:  &sync.Map{},
+}
+
+type application struct {
+	*lifecycle.Lifecycle
+	desktops *sync.Map // 以窗口的 ID 为键名
+}
+
+// NewApplication 返回 GTK 平台的 webview.Application 实现
+//
+// 同一进程中只有一个消息循环，多次调用返回的是同一对象，由 New 创建的窗口同样属于该对象。
+func NewApplication() webview.Application { return defaultApp }
+
+func (a *application) NewWindow(o interface{}) (webview.Desktop, error) {
+	switch opt := o.(type) {
+	case nil:
+		return a.newWindow(nil), nil
+	case *Options:
+		return a.newWindow(opt), nil
+	default:
+		return nil, webview.ErrInvalidOptions()
+	}
+}
+
+func (a *application) Run() { a.Lifecycle.Run(func() { C.run() }) }
+
+func (a *application) add(d *desktop) {
+	a.desktops.Store(d.binder.WindowID(), d)
+	a.Add(d)
+}
+
+func (a *application) desktop(id C.int) (*desktop, bool) {
+	if x, found := a.desktops.Load(int(id)); found {
+		d, ok := x.(*desktop)
+		return d, ok
+	}
+	return nil, false
+}
+
+// 窗口销毁之后调用
+//
+//export closedCallback
+func closedCallback(id C.int) {
+	x, found := defaultApp.desktops.LoadAndDelete(int(id))
+	if !found {
+		return
+	}
+
+	d := x.(*desktop)
+	d.binder.Close()
+	defaultApp.Closed(d)
+}
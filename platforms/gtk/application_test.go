@@ -0,0 +1,28 @@
+// SPDX-License-Identifier: MIT
+
+//go:build linux || openbsd || freebsd || netbsd
+
+package gtk
+
+import (
+	"testing"
+
+	"github.com/issue9/assert/v3"
+
+	"github.com/issue9/webview"
+)
+
+func TestNewApplication(t *testing.T) {
+	a := assert.New(t, false)
+
+	app := NewApplication()
+	a.NotNil(app).Equal(app, NewApplication())
+
+	w, err := app.NewWindow(5)
+	a.Equal(err, webview.ErrInvalidOptions()).Nil(w)
+
+	w, err = app.NewWindow(&Options{Title: "settings"})
+	a.NotError(err).NotNil(w).Equal(w.Title(), "settings")
+	windows := app.Windows()
+	a.Equal(windows[len(windows)-1], w)
+}
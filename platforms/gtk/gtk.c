@@ -7,23 +7,49 @@ WebKitUserContentManager* _userContentManager(GtkWidget* wv) {
     return webkit_web_view_get_user_content_manager(WEBKIT_WEB_VIEW(wv));
 }
 
+// 所有页面都需要的代码，前端通过 window.external.invoke 向 Go 发送消息。
+static const char* external_js = "window.external={invoke:function(s){window.webkit.messageHandlers.external.postMessage(s);}}";
+
 void _add_script(GtkWidget* wv, const char* js) {
     WebKitUserContentManager* m = _userContentManager(wv);
     WebKitUserScript* script = webkit_user_script_new(js, WEBKIT_USER_CONTENT_INJECT_TOP_FRAME, WEBKIT_USER_SCRIPT_INJECT_AT_DOCUMENT_START, NULL, NULL);
     webkit_user_content_manager_add_script(m, script);
 }
 
+// user_data 为 App，用于获取发送消息的窗口和页面地址。
 void _script_message_received(WebKitUserContentManager* m, WebKitJavascriptResult* rslt, gpointer user_data) {
+    App* app = (App*)user_data;
     JSCValue *value = webkit_javascript_result_get_js_value(rslt);
     char* js = jsc_value_to_string(value);
-    messageCallback(js);
+    messageCallback(app->id, js, app->uri == NULL ? (char*)"about:blank" : app->uri);
+    g_free(js);
 }
 
+// 记录已经提交的页面地址
+//
+// webkit_web_view_get_uri 在开始加载新页面时就已经改变，此时发送消息的依然是旧的页面。
+void _load_changed(WebKitWebView* wv, WebKitLoadEvent e, gpointer user_data) {
+    if (e != WEBKIT_LOAD_COMMITTED) {
+        return;
+    }
+
+    App* app = (App*)user_data;
+    g_free(app->uri);
+    app->uri = g_strdup(webkit_web_view_get_uri(wv));
+}
+
+// data 为窗口的 ID
 gboolean _dispatch_cb(gpointer data) {
-    dispatchCallback();
+    dispatchCallback(GPOINTER_TO_INT(data));
     return G_SOURCE_REMOVE;
 }
 
+void _destroy(GtkWidget* win, gpointer user_data) {
+    App* app = (App*)user_data;
+    app->closed = true;
+    closedCallback(app->id);
+}
+
 void _move(GtkWidget* win, int x, int y) {
     gtk_window_move(GTK_WINDOW(win), x, y);
 }
@@ -37,6 +63,14 @@ void _set_size(GtkWidget* win, int w, int h) {
 }
 
 App* create_gtk(bool debug, int x, int y, int w, int h, bool fixed, const char* title) {
+    gtk_init_check(NULL, NULL);
+
+    // App 在窗口关闭之后也不会释放，Go 中的对象依然引用着它。
+    App *app = (App*)malloc(sizeof(App));
+    app->id = 0;
+    app->closed = false;
+    app->uri = NULL;
+
     WebKitSettings* settings = webkit_settings_new();
     webkit_settings_set_enable_developer_extras(settings, debug);
     webkit_settings_set_javascript_can_access_clipboard(settings, true);
@@ -44,9 +78,10 @@ App* create_gtk(bool debug, int x, int y, int w, int h, bool fixed, const char*
     GtkWidget* wv = webkit_web_view_new_with_settings(settings);
 
     WebKitUserContentManager* m = _userContentManager(wv);
-    g_signal_connect(m, "script-message-received::external", G_CALLBACK(_script_message_received), NULL);
+    g_signal_connect(m, "script-message-received::external", G_CALLBACK(_script_message_received), app);
+    g_signal_connect(wv, "load-changed", G_CALLBACK(_load_changed), app);
     webkit_user_content_manager_register_script_message_handler(m, "external");
-    _add_script(wv, "window.external={invoke:function(s){window.webkit.messageHandlers.external.postMessage(s);}}");
+    _add_script(wv, external_js);
 
     GtkWidget* win = gtk_window_new(GTK_WINDOW_TOPLEVEL);
     _move(win, x, y);
@@ -59,10 +94,10 @@ App* create_gtk(bool debug, int x, int y, int w, int h, bool fixed, const char*
         gtk_window_set_resizable(GTK_WINDOW(win), false);
     }
 
-
-    App *app = (App*)malloc(sizeof(App));
     app->win = win;
     app->wv = wv;
+    g_signal_connect(win, "destroy", G_CALLBACK(_destroy), app);
+    gtk_widget_show_all(win);
     return app;
 }
 
@@ -70,6 +105,12 @@ void add_script(App* app, const char* js) {
     _add_script(app->wv, js);
 }
 
+// 删除所有通过 add_script 注入的代码
+void remove_scripts(App* app) {
+    webkit_user_content_manager_remove_all_scripts(_userContentManager(app->wv));
+    _add_script(app->wv, external_js);
+}
+
 void set_size(App* app, int w, int h) {
     _set_size(app->win, w, h);
 }
@@ -108,18 +149,46 @@ void eval(App* app, const char* js) {
     webkit_web_view_run_javascript(WEBKIT_WEB_VIEW(app->wv), js, NULL, NULL, NULL);
 }
 
-void quit(App *app) {
+gboolean _close_cb(gpointer data) {
+    App* app = (App*)data;
+    if (!app->closed) {
+        gtk_widget_destroy(app->win);
+    }
+    return G_SOURCE_REMOVE;
+}
+
+void close_window(App *app) {
+    g_idle_add_full(G_PRIORITY_HIGH_IDLE, _close_cb, app, NULL);
+}
+
+gboolean _quit_cb(gpointer data) {
     gtk_main_quit();
-    free(app);
+    return G_SOURCE_REMOVE;
 }
 
-void run(App* app) {
-    if (gtk_init_check(NULL, NULL)) {
-        gtk_widget_show_all(app->win);
+// 可能在非主线程中调用，也可能在 gtk_main 之前调用。
+void quit() {
+    g_idle_add_full(G_PRIORITY_HIGH_IDLE, _quit_cb, NULL, NULL);
+}
+
+// 所有窗口共用同一个 gtk_main，已经在运行时直接返回。
+void run() {
+    if (gtk_main_level() == 0) {
         gtk_main();
     }
 }
 
-void dispatch() {
-    g_idle_add_full(G_PRIORITY_HIGH_IDLE, _dispatch_cb, NULL, NULL);
+static GThread* main_thread = NULL;
+
+// 记录当前线程为主线程，需要在主线程中调用。
+void set_main_thread() {
+    main_thread = g_thread_self();
+}
+
+bool is_main_thread() {
+    return g_thread_self() == main_thread;
+}
+
+void dispatch(int id) {
+    g_idle_add_full(G_PRIORITY_HIGH_IDLE, _dispatch_cb, GINT_TO_POINTER(id), NULL);
 }
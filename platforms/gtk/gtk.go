@@ -12,6 +12,8 @@ package gtk
 */
 import "C"
 import (
+	"encoding/json"
+	"io"
 	"runtime"
 	"unsafe"
 
@@ -21,19 +23,25 @@ import (
 
 func init() {
 	runtime.LockOSThread()
+	C.set_main_thread()
 }
 
-var binder *pipe.Binder
-
 type desktop struct {
 	title    string
 	position webview.Point
 	size     webview.Size
 
-	app *C.App
+	app     *C.App
+	binder  *pipe.Binder
+	scripts []string // 通过 OnLoad 注入的代码
 }
 
-func New(o *Options) webview.Desktop {
+// New 在默认的 webview.Application 中创建窗口
+//
+// 与 NewApplication().NewWindow(o) 相同。
+func New(o *Options) webview.Desktop { return defaultApp.newWindow(o) }
+
+func (a *application) newWindow(o *Options) *desktop {
 	o = sanitizeOptions(o)
 	x, y, w, h := C.int(o.Position.X), C.int(o.Position.Y), C.int(o.Size.Width), C.int(o.Size.Height)
 
@@ -48,7 +56,12 @@ func New(o *Options) webview.Desktop {
 		app: C.create_gtk(C._Bool(o.Debug), x, y, w, h, C._Bool(o.FixedSize), title),
 	}
 
-	binder = pipe.NewBinder(d, d.eval, func() { C.dispatch() }, o.Error)
+	bo := o.binderOptions()
+	bo.Inject = d.inject
+	d.binder = pipe.NewBinder(d, d.eval, func() { C.dispatch(d.app.id) }, bo)
+	d.app.id = C.int(d.binder.WindowID())
+
+	a.add(d)
 
 	return d
 }
@@ -72,21 +85,64 @@ func (d *desktop) Load(url string) {
 }
 
 func (d *desktop) OnLoad(js string) {
+	d.scripts = append(d.scripts, js)
+	d.addScript(js)
+}
+
+// 替换 Binder 注入的代码
+//
+// WebKitUserContentManager 只能删除所有的代码，需要重新注入 OnLoad 中的代码。
+func (d *desktop) inject(js string) {
+	C.remove_scripts(d.app)
+	for _, s := range d.scripts {
+		d.addScript(s)
+	}
+	if js != "" {
+		d.addScript(js)
+	}
+}
+
+func (d *desktop) addScript(js string) {
 	t := C.CString(js)
 	defer C.free(unsafe.Pointer(t))
 	C.add_script(d.app, t)
 }
 
 func (d *desktop) Bind(name string, f interface{}) error {
-	return binder.Bind(name, f)
+	return d.binder.Bind(name, f)
+}
+
+func (d *desktop) BindObject(namespace string, obj interface{}, mapper webview.NameMapper) error {
+	return d.binder.BindObject(namespace, obj, mapper)
 }
 
-func (d *desktop) Run() {
-	C.run(d.app)
+func (d *desktop) Unbind(name string) { d.binder.Unbind(name) }
+
+func (d *desktop) TypeScript(w io.Writer) error { return d.binder.TypeScript(w) }
+
+func (d *desktop) Emit(event string, payload interface{}) error {
+	return d.binder.Emit(event, payload)
 }
 
+func (d *desktop) On(event string, f func(json.RawMessage)) { d.binder.On(event, f) }
+
+func (d *desktop) Off(event string) { d.binder.Off(event) }
+
+func (d *desktop) Dispatch(f func()) { d.binder.Dispatch(f) }
+
+func (d *desktop) DispatchSync(f func()) error {
+	if C.is_main_thread() {
+		f()
+		return nil
+	}
+	return d.binder.DispatchSync(f)
+}
+
+func (d *desktop) Run() { defaultApp.Run() }
+
 func (d *desktop) Close() {
-	C.quit(d.app)
+	d.binder.Close()
+	C.close_window(d.app)
 }
 
 func (d *desktop) Title() string { return d.title }
@@ -119,11 +175,18 @@ func (d *desktop) SetSize(s webview.Size, h webview.Hint) {
 }
 
 //export dispatchCallback
-func dispatchCallback() {
-	binder.DispatchCallback()
+func dispatchCallback(id C.int) {
+	if d, found := defaultApp.desktop(id); found {
+		d.binder.DispatchCallback()
+	}
 }
 
+// url 为发送消息的页面地址，webkit2gtk 无法获取发送消息的框架，
+// 由 Binder 根据运行时持有的令牌过滤掉其它框架的消息。
+//
 //export messageCallback
-func messageCallback(msg *C.char) {
-	binder.MessageHandler(C.GoString(msg))
+func messageCallback(id C.int, msg, url *C.char) {
+	if d, found := defaultApp.desktop(id); found {
+		d.binder.HandleMessage(C.GoString(msg), pipe.Source{URL: C.GoString(url)})
+	}
 }
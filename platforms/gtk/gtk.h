@@ -13,14 +13,23 @@
 typedef struct {
     GtkWidget* win;
     GtkWidget* wv;
+    int id; // 窗口的 ID，回调函数根据该值查找对应的窗口。
+    bool closed;
+    char* uri; // 最后一次提交加载的页面地址
 } App;
 
-void dispatch();
+void set_main_thread();
+
+bool is_main_thread();
+
+void dispatch(int id);
 
 App* create_gtk(bool debug, int x, int y, int w, int h, bool fixed, const char* title);
 
 void add_script(App* app, const char* js);
 
+void remove_scripts(App* app);
+
 void set_size(App* app, int w, int h);
 
 void set_min_size(App* app, int w, int h);;
@@ -37,8 +46,10 @@ void load_html(App* app, const char* html);;
 
 void eval(App* app, const char* js);
 
-void quit(App *app);
+void close_window(App *app);
+
+void quit();
 
-void run(App* app);
+void run();
 
 #endif
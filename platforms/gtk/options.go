@@ -6,8 +6,10 @@ package gtk
 
 import (
 	"log"
+	"time"
 
 	"github.com/issue9/webview"
+	"github.com/issue9/webview/internal/pipe"
 	"github.com/issue9/webview/internal/presets"
 )
 
@@ -32,6 +34,62 @@ type Options struct {
 	//
 	// 部分非致命的错误经由此输出，如果为空，则采用 log.Default() 。
 	Error *log.Logger
+
+	// Workers 同时执行绑定方法的最大数量
+	//
+	// 绑定的方法不在主线程上执行，超出此数量的调用会排队等待，队列的长度由 Queue 指定。
+	// 如果为 0，则采用默认值。
+	Workers int
+
+	// Queue 等待执行的调用的最大数量
+	//
+	// 队列已满时，新的调用会以 [webview.ErrBusy] 拒绝。
+	// 如果为 0，则采用默认值。
+	Queue int
+
+	// MethodWorkers 单个绑定方法同时执行的最大数量
+	//
+	// 如果为 0，则与 Workers 相同。
+	MethodWorkers int
+
+	// MethodNotFound 前端调用了不存在的方法时的回调
+	//
+	// 可用于记录日志或是统计，如果为空，则输出到 Error。
+	MethodNotFound func(method string)
+
+	// Codec 前后端之间传递数据时采用的编码方式
+	//
+	// 如果为空，则采用 codec.JSON()。
+	Codec webview.Codec
+
+	// Middlewares 应用于所有绑定方法的中间件
+	//
+	// 按顺序由外向内包装，即第一个中间件最先执行。
+	Middlewares []webview.Middleware
+
+	// RePanic 绑定的方法发生 panic 时是否在记录日志之后重新 panic
+	//
+	// 默认情况下 panic 会被恢复，前端得到 webview.ErrInternal 错误，
+	// 设置为 true 会导致整个程序崩溃，仅用于调试。
+	RePanic bool
+
+	// Timeout 所有绑定方法的默认超时时间
+	//
+	// 前端通过 withOptions 指定的超时时间以及 webview.WithTimeout 的优先级更高，
+	// 如果为 0，表示不限制。
+	Timeout time.Duration
+
+	// Strict 是否以严格模式解码绑定方法的参数
+	//
+	// 需要 Codec 实现 webview.StrictCodec，否则会 panic。
+	// 开启之后参数中包含未知的字段也会返回错误，具体可参考 webview.StrictCodec。
+	Strict bool
+
+	// Origins 允许调用绑定方法的页面
+	//
+	// 仅作用于未通过 webview.WithOrigins 指定来源的方法，以及前端发送的事件，
+	// 格式可参考 webview.WithOrigins，为空表示不限制。
+	Origins []string
 }
 
 func sanitizeOptions(o *Options) *Options {
@@ -57,3 +115,19 @@ func sanitizeOptions(o *Options) *Options {
 
 	return o
 }
+
+func (o *Options) binderOptions() *pipe.Options {
+	return &pipe.Options{
+		Error:          o.Error,
+		Workers:        o.Workers,
+		Queue:          o.Queue,
+		MethodWorkers:  o.MethodWorkers,
+		MethodNotFound: o.MethodNotFound,
+		Codec:          o.Codec,
+		Middlewares:    o.Middlewares,
+		RePanic:        o.RePanic,
+		Timeout:        o.Timeout,
+		Strict:         o.Strict,
+		Origins:        o.Origins,
+	}
+}
@@ -0,0 +1,63 @@
+// SPDX-License-Identifier: MIT
+
+//go:build windows
+
+package windows
+
+import (
+	"golang.org/x/sys/windows"
+
+	"github.com/issue9/webview"
+	"github.com/issue9/webview/internal/lifecycle"
+	"github.com/issue9/webview/internal/windows/w32"
+)
+
+// 包的初始化在主线程中进行，edge 包已经锁定了主线程。
+var mainThread = uintptr(windows.GetCurrentThreadId())
+
+// 整个进程只有一个消息循环，所以也只有一个 application。
+var defaultApp = &application{
+	Lifecycle: lifecycle.New(func() { w32.PostThreadMessage(mainThread, w32.WMQuit, 0, 0) }), // 可能在非主线程中调用
+}
+
+type application struct {
+	*lifecycle.Lifecycle
+}
+
+// NewApplication 返回 windows 平台的 webview.Application 实现
+//
+// 同一进程中只有一个消息循环，多次调用返回的是同一对象，由 New 创建的窗口同样属于该对象。
+func NewApplication() webview.Application { return defaultApp }
+
+func (a *application) NewWindow(o interface{}) (webview.Desktop, error) {
+	switch opt := o.(type) {
+	case nil:
+		return a.newWindow(nil)
+	case *Options:
+		return a.newWindow(opt)
+	default:
+		return nil, webview.ErrInvalidOptions()
+	}
+}
+
+func (a *application) Run() {
+	a.Lifecycle.Run(func() {
+		var msg w32.Msg
+		for {
+			w32.GetMessage(&msg, 0, 0, 0)
+			if msg.Message == w32.WMApp {
+				if w, found := getWindowContext(msg.WParam); found {
+					w.binder.DispatchCallback()
+				}
+			} else if msg.Message == w32.WMQuit {
+				return
+			}
+			r := w32.GetAncestor(uintptr(msg.Hwnd), w32.GARoot)
+			if w32.IsDialogMessage(r, &msg) {
+				continue
+			}
+			w32.TranslateMessage(&msg)
+			w32.DispatchMessage(&msg)
+		}
+	})
+}
@@ -13,7 +13,12 @@ import (
 	"github.com/issue9/webview/internal/windows/w32"
 )
 
-var windowContext = &sync.Map{}
+var (
+	windowContext = &sync.Map{}
+
+	// 所有窗口共用同一个窗口类，也只需要一个回调函数。
+	wndProcCallback = windows.NewCallback(wndProc)
+)
 
 func getWindowContext(wnd uintptr) (*desktop, bool) {
 	if x, found := windowContext.Load(wnd); found {
@@ -27,6 +32,11 @@ func setWindowContext(wnd uintptr, data *desktop) {
 	windowContext.Store(wnd, data)
 }
 
+func deleteWindowContext(wnd uintptr) bool {
+	_, found := windowContext.LoadAndDelete(wnd)
+	return found
+}
+
 func (d *desktop) createWindow(o *Options) error {
 	var inst windows.Handle
 	if err := windows.GetModuleHandleEx(0, nil, &inst); err != nil {
@@ -46,9 +56,9 @@ func (d *desktop) createWindow(o *Options) error {
 		LpszClassName: className,
 		HIcon:         windows.Handle(icon),
 		HIconSm:       windows.Handle(icon),
-		LpfnWndProc:   windows.NewCallback(wndProc),
+		LpfnWndProc:   wndProcCallback,
 	}
-	if err := w32.RegisterClassEx(&wc); err != nil {
+	if err := w32.RegisterClassEx(&wc); err != nil && err != windows.ERROR_CLASS_ALREADY_EXISTS { // 多个窗口共用窗口类
 		return err
 	}
 
@@ -107,7 +117,7 @@ func wndProc(hwnd, msg, wp, lp uintptr) uintptr {
 		case w32.WMClose:
 			w32.DestroyWindow(hwnd)
 		case w32.WMDestroy:
-			w.Close()
+			w.destroy()
 		case w32.WMGetMinMaxInfo:
 			lpmmi := (*w32.MinMaxInfo)(unsafe.Pointer(lp))
 			if w.maxSize.Width > 0 && w.maxSize.Height > 0 {
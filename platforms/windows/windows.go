@@ -8,6 +8,8 @@
 package windows
 
 import (
+	"encoding/json"
+	"io"
 	"log"
 
 	"golang.org/x/sys/windows"
@@ -31,14 +33,21 @@ type desktop struct {
 	autofocus  bool
 	errlog     *log.Logger
 
-	binder *pipe.Binder
+	binder   *pipe.Binder
+	stubs    string // Binder 注入的代码的 ID
+	stubsGen int    // Binder 注入代码的次数，用于删除异步返回的过期代码。
 }
 
-func New(o *Options) (webview.Desktop, error) {
+// New 在默认的 webview.Application 中创建窗口
+//
+// 与 NewApplication().NewWindow(o) 相同。
+func New(o *Options) (webview.Desktop, error) { return defaultApp.newWindow(o) }
+
+func (a *application) newWindow(o *Options) (webview.Desktop, error) {
 	o = sanitizeOptions(o)
 
 	d := &desktop{
-		mainThread: uintptr(windows.GetCurrentThreadId()),
+		mainThread: mainThread,
 		title:      o.Title,
 		position:   o.Position,
 		size:       o.Size,
@@ -47,10 +56,7 @@ func New(o *Options) (webview.Desktop, error) {
 		errlog:     o.Error,
 	}
 
-	// chromium 和 binder 相互引用，注意初始化顺序。
 	chromium := edge.NewChromium(o.Error)
-	d.binder = pipe.NewBinder(d, chromium.Eval, func() { w32.PostThreadMessage(d.mainThread, w32.WMApp, 0, 0) }, o.Error)
-	chromium.MessageCallback = d.binder.MessageHandler
 	chromium.DataPath = o.DataPath
 	chromium.SetPermission(edge.CoreWebView2PermissionKindClipboardRead, edge.CoreWebView2PermissionStateAllow)
 	d.chromium = chromium
@@ -59,6 +65,13 @@ func New(o *Options) (webview.Desktop, error) {
 		return nil, err
 	}
 
+	// NewBinder 需要调用 OnLoad，必须在 chromium 初始化之后。
+	// 多个窗口共用主线程的消息循环，WMApp 消息以 wParam 指定窗口。
+	bo := o.binderOptions()
+	bo.Inject = d.inject
+	d.binder = pipe.NewBinder(d, chromium.Eval, func() { w32.PostThreadMessage(d.mainThread, w32.WMApp, d.hwnd, 0) }, bo)
+	chromium.MessageCallback = func(msg, source string) { d.binder.HandleMessage(msg, pipe.Source{URL: source}) }
+
 	settings, err := chromium.GetSettings()
 	if err != nil {
 		return nil, err
@@ -72,6 +85,7 @@ func New(o *Options) (webview.Desktop, error) {
 		return nil, err
 	}
 
+	a.Add(d)
 	return d, nil
 }
 
@@ -79,32 +93,75 @@ func (d *desktop) Load(url string) { d.chromium.Navigate(url) }
 
 func (d *desktop) SetHTML(html string) { d.chromium.NavigateToString(html) }
 
-func (d *desktop) Run() {
-	var msg w32.Msg
-	for {
-		w32.GetMessage(&msg, 0, 0, 0)
-		if msg.Message == w32.WMApp {
-			d.binder.DispatchCallback()
-		} else if msg.Message == w32.WMQuit {
-			return
-		}
-		r := w32.GetAncestor(uintptr(msg.Hwnd), w32.GARoot)
-		if w32.IsDialogMessage(r, &msg) {
-			continue
-		}
-		w32.TranslateMessage(&msg)
-		w32.DispatchMessage(&msg)
+func (d *desktop) Dispatch(f func()) { d.binder.Dispatch(f) }
+
+func (d *desktop) DispatchSync(f func()) error {
+	if uintptr(windows.GetCurrentThreadId()) == d.mainThread {
+		f()
+		return nil
 	}
+	return d.binder.DispatchSync(f)
 }
 
-func (d *desktop) Close() { w32.PostQuitMessage(0) }
+func (d *desktop) Run() { defaultApp.Run() }
+
+func (d *desktop) Close() {
+	d.binder.Close()
+	w32.PostMessage(d.hwnd, w32.WMClose, 0, 0) // 可能在非主线程中调用
+}
+
+// 窗口销毁之后调用
+func (d *desktop) destroy() {
+	if deleteWindowContext(d.hwnd) {
+		d.binder.Close()
+		defaultApp.Closed(d)
+	}
+}
 
 func (d *desktop) OnLoad(js string) { d.chromium.Init(js) }
 
+// 替换 Binder 注入的代码
+func (d *desktop) inject(js string) {
+	d.stubsGen++
+	gen := d.stubsGen
+
+	if d.stubs != "" {
+		d.chromium.RemoveScript(d.stubs)
+		d.stubs = ""
+	}
+	if js == "" {
+		return
+	}
+
+	d.chromium.AddScript(js, func(id string) {
+		if gen == d.stubsGen {
+			d.stubs = id
+		} else { // 在返回 ID 之前已经被替换
+			d.chromium.RemoveScript(id)
+		}
+	})
+}
+
 func (d *desktop) Bind(name string, f interface{}) error {
 	return d.binder.Bind(name, f)
 }
 
+func (d *desktop) BindObject(namespace string, obj interface{}, mapper webview.NameMapper) error {
+	return d.binder.BindObject(namespace, obj, mapper)
+}
+
+func (d *desktop) Unbind(name string) { d.binder.Unbind(name) }
+
+func (d *desktop) TypeScript(w io.Writer) error { return d.binder.TypeScript(w) }
+
+func (d *desktop) Emit(event string, payload interface{}) error {
+	return d.binder.Emit(event, payload)
+}
+
+func (d *desktop) On(event string, f func(json.RawMessage)) { d.binder.On(event, f) }
+
+func (d *desktop) Off(event string) { d.binder.Off(event) }
+
 func (d *desktop) Title() string { return d.title }
 
 func (d *desktop) SetTitle(title string) {
@@ -19,3 +19,19 @@ func TestNew(t *testing.T) {
 	a.NotError(err).NotNil(d)
 	webviewtest.Desktop(d)
 }
+
+func TestNew_multiple(t *testing.T) {
+	a := assert.New(t, false)
+
+	d1, err := New(nil)
+	a.NotError(err).NotNil(d1)
+	d2, err := New(nil)
+	a.NotError(err).NotNil(d2)
+
+	w1, w2 := d1.(*desktop), d2.(*desktop)
+	a.NotEqual(w1.hwnd, w2.hwnd).
+		NotEqual(w1.binder.WindowID(), w2.binder.WindowID())
+
+	w, found := getWindowContext(w2.hwnd)
+	a.True(found).Equal(w, w2)
+}
@@ -2,6 +2,14 @@
 
 package webview
 
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+	"unicode"
+)
+
 // App 基于 webview 应用的基本接口
 type App interface {
 	// SetHTML 直接将内容设置为 HTML
@@ -18,12 +26,99 @@ type App interface {
 	// Bind 绑定方法至前端
 	//
 	// f 必须是一个函数，反加值可以是单个值，或是两值，如果是两个值，那么其第二个必须得是 error。
+	// f 最前面的参数可以是 *CallInfo，用于获取调用方的信息，由 Binder 自动填充；
+	// 也可以是 context.Context，在程序关闭、页面跳转或是前端取消调用时该值会被取消，两者的顺序不限。
+	// 这些类型以及 Stream 出现在其它位置时返回 [ErrBindFuncParamInvalid]。
+	// f 末尾的指针类型参数在前端调用时可以省略，省略的参数为 nil。
+	//
+	// f 返回的错误会以 {message, code, data} 形式的对象传递给前端，
+	// 其中 code 和 data 分别来自 CodeError 和 DataError 接口。
+	//
+	// 如果 f 的返回值为 <-chan T，或是包含 Stream 类型的参数，前端得到的是一个流对象，具体可参考 Stream。
+	//
+	// 前端的 ArrayBuffer 和 TypedArray 等可以作为 []byte 类型的参数，
+	// 而直接返回的 []byte 在前端则为 Uint8Array。
+	//
+	// 前端可以通过 withOptions 指定 AbortSignal 或是超时时间（毫秒），
+	// 两者都会取消 f 的 context.Context，超时的调用以 ErrorCodeTimeout 错误拒绝：
+	//
+	//	add.withOptions({signal: controller.signal, timeout: 1000})(1, 2);
+	//
+	// f 也可以是由 WithTimeout 包装的函数，用于指定前端未指定时的默认超时时间；
+	// 或是由 WithOrigins 包装的函数，用于限制可以调用该方法的页面。
+	//
+	// 参数在解码之后会根据结构体字段的 validate 标签进行验证，也可以实现 Validator 接口，
+	// 未通过验证的调用不会执行 f，而是以 ValidationError 拒绝，具体规则可参考 Validator。
+	//
+	// name 不能与 BindObject 的命名空间相同，否则返回 [ErrNameConflict]。
 	Bind(name string, f interface{}) error
 
+	// BindObject 将 obj 的导出方法绑定至前端的 window[namespace] 对象上
+	//
+	// mapper 用于转换方法在前端的名称，为空表示采用 Go 中的方法名，返回空字符串表示不绑定该方法；
+	// obj 可以实现 Excluder 接口以排除部分方法。各方法的要求与 Bind 中的 f 相同。
+	// obj 同样可以由 WithTimeout 和 WithOrigins 包装，其设置应用于所有的方法。
+	// obj 为 nil 指针时返回 [ErrBindObjectNil]，namespace 与 Bind 的方法名相同时返回 [ErrNameConflict]。
+	BindObject(namespace string, obj interface{}, mapper NameMapper) error
+
+	// TypeScript 将所有绑定方法的 TypeScript 声明写入 w
+	//
+	// 参数和返回值的类型根据 Go 中的类型按 encoding/json 的规则生成，
+	// 结构体会被声明为同名的 interface。也可以通过 cmd/webview-dts 生成。
+	TypeScript(w io.Writer) error
+
+	// Unbind 解除由 Bind 绑定的方法
+	//
+	// 同时会删除当前页面中的同名方法，之后加载的页面中也不再注入该方法。
+	// 如果 name 为 BindObject 绑定的命名空间，则解除该命名空间下的所有方法。
+	Unbind(name string)
+
+	// Emit 向前端发送事件
+	//
+	// 前端可以通过 window.webview.on、window.webview.once 订阅事件，
+	// 通过 window.webview.off 取消订阅。payload 经由 Codec 编码之后传递给订阅的函数。
+	//
+	// 可以在任意 goroutine 中调用。
+	Emit(event string, payload interface{}) error
+
+	// On 订阅前端发送的事件
+	//
+	// 前端通过 window.webview.emit(event, payload) 发送事件，与 Bind 不同，事件没有返回值。
+	// 同一事件可以多次订阅，f 在主线程之外执行，payload 为前端传递的数据，
+	// 不论采用何种 Codec，payload 都会被转换为 JSON 格式。
+	On(event string, f func(payload json.RawMessage))
+
+	// Off 取消 event 事件的所有订阅
+	Off(event string)
+
+	// Dispatch 将 f 放入主线程异步执行
+	//
+	// 界面相关的操作，比如 SetTitle 等，都应该在主线程中进行，
+	// 其它 goroutine 中可以通过此方法进行：
+	//
+	//	go func() {
+	//	    app.Dispatch(func() { app.SetTitle("done") })
+	//	}()
+	//
+	// 可以在任意 goroutine 中调用，在 Close 之后 f 不会再被执行。
+	Dispatch(f func())
+
+	// DispatchSync 将 f 放入主线程执行并等待其完成
+	//
+	// 在主线程中调用时直接执行 f，所以也可以在由 Dispatch 和 DispatchSync 执行的函数中调用。
+	// 如果 f 未执行 App 就已经关闭，返回 [ErrClosed]。
+	DispatchSync(f func()) error
+
 	// Run 运行程序
+	//
+	// 同一进程中的多个窗口共用一个消息循环，只需调用其中任意一个的 Run，
+	// 默认在所有窗口都关闭之后返回，具体可参考 [Application]。
 	Run()
 
 	// Close 关闭服务
+	//
+	// 对于桌面应用，关闭的是当前窗口，不会影响其它窗口，最后一个窗口关闭之后 Run 返回。
+	// 可以在任意 goroutine 中调用，包括绑定的方法中。
 	Close()
 }
 
@@ -65,3 +160,274 @@ const (
 	HintMin
 	HintMax
 )
+
+// Stream 向前端推送数据的流
+//
+// 绑定的方法可以声明一个 Stream 类型的参数（位于第一个，或是在 *CallInfo 和 context.Context 之后），
+// 通过 Send 逐条推送数据，方法返回时流结束，返回的错误会作为流的错误传递给前端；
+// 也可以直接返回 <-chan T，每一条数据都会推送给前端，直到通道关闭或是调用被取消。
+// 在调用被取消之后，向通道写入数据的 goroutine 应该自行退出，通常可以通过 context.Context 获知。
+//
+// 对于此类方法，前端得到的不是 Promise，而是一个流对象：
+//
+//	const s = logs("app.log");
+//	for await (const line of s) { ... } // 异步迭代器
+//	s.each((line) => { ... }).then(...); // 回调
+//	s.cancel(); // 取消
+type Stream interface {
+	// Send 向前端推送一条数据
+	//
+	// v 经由 Codec 编码，调用被取消之后会返回 context 的错误。
+	Send(v interface{}) error
+}
+
+// Callback 前端作为参数传递的回调函数
+//
+// 绑定的方法可以声明此类型的参数，前端在该位置传递一个函数，
+// 之后 Go 可以在任意 goroutine 中通过 Call 调用该函数：
+//
+//	app.Bind("scan", func(dir string, progress webview.Callback) error {
+//	    return progress.Call(50, "half")
+//	})
+//
+//	scan("/home", (percent, msg) => { ... });
+//
+// 只能作为绑定方法的直接参数，不能作为结构体的字段等。
+//
+// 回调函数属于传递它的那次调用，在调用结束（包括超时和取消，对于流则是流结束）之后自动释放。
+// 如果需要在调用结束之后继续使用，比如保存下来用于订阅，需要在调用结束之前调用 Keep，
+// 之后由 Go 负责在不再需要时调用 Release。不论是否保留，页面跳转之后都会自动释放。
+// 释放之后的调用会返回 [ErrCallbackReleased]。
+type Callback interface {
+	// Call 调用前端的回调函数
+	//
+	// args 经由 Codec 编码之后依次作为回调函数的参数。
+	// 回调函数在主线程上异步执行，Call 不会等待其完成，也无法得到其返回值。
+	Call(args ...interface{}) error
+
+	// Keep 保留前端的回调函数
+	//
+	// 保留的回调函数在调用结束之后依然有效，直到调用 Release 或是页面跳转。
+	// 需要在调用结束之前调用，否则返回 [ErrCallbackReleased]。
+	Keep() error
+
+	// Release 释放前端的回调函数
+	//
+	// 对于不再使用的回调函数，可以调用此方法提前释放。
+	Release()
+}
+
+// TimeoutFunc 带默认超时时间的绑定方法
+//
+// 由 [WithTimeout] 创建，可作为 [App.Bind] 的参数。
+type TimeoutFunc struct {
+	Func    interface{}
+	Timeout time.Duration
+}
+
+// WithTimeout 为绑定的方法 f 指定默认的超时时间
+//
+// 前端未通过 withOptions 指定超时时间时，调用超过 timeout 即以 [ErrorCodeTimeout] 错误拒绝，
+// 即使 f 还未返回，f 的 context.Context 也会同时被取消：
+//
+//	app.Bind("search", webview.WithTimeout(search, 5*time.Second))
+func WithTimeout(f interface{}, timeout time.Duration) *TimeoutFunc {
+	return &TimeoutFunc{Func: f, Timeout: timeout}
+}
+
+// OriginFunc 限制了调用来源的绑定方法
+//
+// 由 [WithOrigins] 创建，可作为 [App.Bind] 和 [App.BindObject] 的参数。
+type OriginFunc struct {
+	Func    interface{}
+	Origins []string
+}
+
+// WithOrigins 限制只有 origins 中的页面才能调用 f
+//
+// origin 的格式与前端的 location.origin 相同，比如 https://example.com，
+// 由 SetHTML 加载的页面为 null，* 表示任意来源。
+// 发起调用的页面以及所在框架的 origin 都必须在 origins 之中，
+// 否则前端以 [ErrorCodeForbidden] 错误拒绝，且 f 不会被执行。
+// 如果 origins 为空，则表示不限制。
+//
+// f 也可以是由 [WithTimeout] 包装的函数，反之亦然：
+//
+//	app.Bind("save", webview.WithOrigins(save, "https://app.example.com"))
+func WithOrigins(f interface{}, origins ...string) *OriginFunc {
+	return &OriginFunc{Func: f, Origins: origins}
+}
+
+// Validator 参数的验证接口
+//
+// 绑定方法的参数在解码之后会根据结构体字段的 validate 标签进行验证，多个规则以逗号分隔：
+//   - omitempty 值为空值时跳过其它规则，空值的定义与 encoding/json 的 omitempty 相同；
+//   - required 值不能为零值，指针、切片和 map 则不能为 nil；
+//   - min=n 和 max=n 数值的大小范围，对于字符串、切片、数组和 map 则为长度范围；
+//   - len=n 字符串、切片、数组和 map 的长度，字符串的长度以字符计算；
+//   - oneof=a b c 值只能是以空格分隔的值之一，仅用于字符串和数值；
+//   - regexp=expr 字符串需要匹配正则表达式，由于 expr 中可能包含逗号，只能是最后一个规则；
+//
+// 嵌套的结构体以及切片和 map 中的元素同样会被验证，无效的标签会在 Bind 时返回错误：
+//
+//	type User struct {
+//	    Name  string   `json:"name" validate:"required,max=20"`
+//	    Email string   `json:"email" validate:"omitempty,regexp=^.+@.+$"`
+//	    Role  string   `json:"role" validate:"oneof=admin user"`
+//	    Tags  []string `json:"tags" validate:"max=5"`
+//	}
+//
+// 参数如果实现了 Validator，在标签验证通过之后还会调用 Validate，
+// 返回的 [ValidationError] 会与当前参数合并，其它错误则作为当前值的错误信息。
+type Validator interface {
+	Validate() error
+}
+
+// Invocation 前端对绑定方法的一次调用
+type Invocation struct {
+	// Context 调用的上下文
+	//
+	// 即传递给绑定方法的 context.Context，中间件可以替换为其派生的对象。
+	Context context.Context
+
+	// Method 调用的方法名
+	//
+	// 由 BindObject 绑定的方法为 namespace.name 的形式。
+	Method string
+
+	// Params 由 Codec 编码的原始参数
+	//
+	// 中间件可以修改其内容，之后的中间件和绑定的方法得到的是修改后的值。
+	Params []string
+
+	// Caller 调用方的信息
+	//
+	// 与传递给绑定方法的 *CallInfo 为同一对象，不应该修改其内容。
+	Caller *CallInfo
+}
+
+// CallInfo 调用方的信息
+//
+// 绑定方法的第一个参数如果为 *CallInfo，Binder 会自动填充该参数，前端调用时不需要传递：
+//
+//	app.Bind("save", func(info *webview.CallInfo, ctx context.Context, data string) error {
+//	    log.Println(info.WindowID, info.URL, data)
+//	    return nil
+//	})
+type CallInfo struct {
+	// WindowID 发起调用的窗口
+	//
+	// 同一进程中的每个窗口都有唯一的 ID。
+	WindowID int
+
+	// URL 发起调用的页面地址
+	//
+	// 由平台提供，平台无法提供时由前端报告。
+	URL string
+
+	// Origin 发起调用的框架的 origin
+	//
+	// 格式与前端的 location.origin 相同。
+	Origin string
+
+	// ID 调用在前端的序号
+	//
+	// 同一页面中唯一，可用于关联日志。
+	ID int
+}
+
+// Handler 执行调用并返回结果
+//
+// 返回值与绑定方法的返回值相同，之后会经由 Codec 编码并传递给前端。
+type Handler func(inv *Invocation) (interface{}, error)
+
+// Middleware 中间件
+//
+// 中间件包装了对绑定方法的调用，可用于实现日志、计时、权限验证等功能：
+//
+//	func(next webview.Handler) webview.Handler {
+//	    return func(inv *webview.Invocation) (interface{}, error) {
+//	        start := time.Now()
+//	        ret, err := next(inv)
+//	        log.Println(inv.Method, time.Since(start), err)
+//	        return ret, err
+//	    }
+//	}
+type Middleware func(next Handler) Handler
+
+// Codec 前后端之间传递数据时采用的编码方式
+//
+// 绑定方法的参数和返回值、事件的数据等都经由 Codec 编码，
+// 默认为 JSON，codec 包中提供了几种常用的实现。
+type Codec interface {
+	// Marshal 编码 v
+	//
+	// 返回的内容会以字符串的形式传递给前端的 decode，所以必须是合法的 UTF-8 文本，
+	// 二进制格式的编码需要自行转换，比如 base64。
+	Marshal(v interface{}) ([]byte, error)
+
+	// Unmarshal 将前端 encode 生成的内容解码至 v
+	Unmarshal(data []byte, v interface{}) error
+
+	// JS 前端的实现
+	//
+	// 返回一个 JS 表达式，其值为包含 encode(value) 和 decode(data) 两个方法的对象，
+	// 两者分别与 Unmarshal 和 Marshal 相对应，encode 返回的也必须是字符串。
+	// 其中可以使用 window._rpc.base64(bytes) 和 window._rpc.bytes(base64)
+	// 在 Uint8Array 与 base64 之间相互转换。
+	JS() string
+}
+
+// StrictCodec 支持严格解码的 Codec
+//
+// 在开启了严格模式之后，绑定方法的参数会采用 UnmarshalStrict 解码。
+type StrictCodec interface {
+	Codec
+
+	// UnmarshalStrict 以严格模式将前端 encode 生成的内容解码至 v
+	//
+	// 与 Unmarshal 的区别在于：
+	//   - 结构体中不存在的字段会返回错误；
+	//   - interface{} 中的数值解码为 json.Number 而不是 float64；
+	//   - 数据之后存在多余的内容会返回错误；
+	UnmarshalStrict(data []byte, v interface{}) error
+}
+
+// JSONCodec 编码结果为 JSON 的 Codec
+//
+// 对于此类 Codec，前端传递的参数和事件数据会直接嵌入到消息的 JSON 中，
+// 而不是作为字符串，可以省去一次转义和解析。
+type JSONCodec interface {
+	Codec
+
+	// IsJSON Marshal 和前端 encode 的结果是否为 JSON
+	IsJSON() bool
+}
+
+// NameMapper 将 Go 中的方法名转换为前端的名称
+//
+// 返回空字符串表示不绑定该方法。
+type NameMapper func(method string) string
+
+// Excluder 由 BindObject 绑定的对象可以实现此接口以排除部分方法
+type Excluder interface {
+	// ExcludeMethods 返回不需要绑定的方法
+	//
+	// 返回值为 Go 中的方法名，ExcludeMethods 本身也不会被绑定。
+	ExcludeMethods() []string
+}
+
+// LowerCamelCase 将方法名转换为小驼峰形式
+//
+// 比如 GetUser 转换为 getUser，URLPath 转换为 urlPath，ID 转换为 id。
+func LowerCamelCase(method string) string {
+	rs := []rune(method)
+	for i := 0; i < len(rs) && unicode.IsUpper(rs[i]); i++ {
+		// 多个连续的大写字母，最后一个之后跟着小写字母，则最后一个为下一个单词的开始，比如 URLPath 中的 P。
+		if i > 0 && i+1 < len(rs) && unicode.IsLower(rs[i+1]) {
+			break
+		}
+		rs[i] = unicode.ToLower(rs[i])
+	}
+	return string(rs)
+}
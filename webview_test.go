@@ -0,0 +1,21 @@
+// SPDX-License-Identifier: MIT
+
+package webview
+
+import (
+	"testing"
+
+	"github.com/issue9/assert/v3"
+)
+
+func TestLowerCamelCase(t *testing.T) {
+	a := assert.New(t, false)
+
+	a.Equal(LowerCamelCase("GetUser"), "getUser").
+		Equal(LowerCamelCase("URLPath"), "urlPath").
+		Equal(LowerCamelCase("ID"), "id").
+		Equal(LowerCamelCase("A"), "a").
+		Equal(LowerCamelCase("get"), "get").
+		Equal(LowerCamelCase(""), "").
+		Equal(LowerCamelCase("ÄBc"), "äBc")
+}
@@ -0,0 +1,78 @@
+// SPDX-License-Identifier: MIT
+
+package webviewtest
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/issue9/webview"
+	"github.com/issue9/webview/internal/pipe"
+)
+
+type headless struct {
+	binder *pipe.Binder
+	done   chan struct{}
+	once   *sync.Once
+}
+
+// NewApp 声明一个没有界面的 webview.App 实现
+//
+// 绑定相关的操作都是有效的，但是不会加载和显示任何页面，Run 会一直阻塞直到 Close 被调用。
+// 可用于生成 TypeScript 声明等不需要界面的场合。
+func NewApp() webview.App {
+	a := &headless{
+		done: make(chan struct{}),
+		once: &sync.Once{},
+	}
+	a.binder = pipe.NewBinder(a, func(string) {}, func() { a.binder.DispatchCallback() }, nil)
+	return a
+}
+
+func (a *headless) SetHTML(string) {}
+
+func (a *headless) Load(string) {}
+
+func (a *headless) OnLoad(string) {}
+
+func (a *headless) Bind(name string, f interface{}) error { return a.binder.Bind(name, f) }
+
+func (a *headless) BindObject(namespace string, obj interface{}, mapper webview.NameMapper) error {
+	return a.binder.BindObject(namespace, obj, mapper)
+}
+
+func (a *headless) Unbind(name string) { a.binder.Unbind(name) }
+
+func (a *headless) TypeScript(w io.Writer) error { return a.binder.TypeScript(w) }
+
+func (a *headless) Emit(event string, payload interface{}) error {
+	return a.binder.Emit(event, payload)
+}
+
+func (a *headless) On(event string, f func(json.RawMessage)) { a.binder.On(event, f) }
+
+func (a *headless) Off(event string) { a.binder.Off(event) }
+
+// 没有主线程，f 在 DispatchCallback 的调用者中执行，即当前 goroutine。
+func (a *headless) Dispatch(f func()) { a.binder.Dispatch(f) }
+
+// 与 Dispatch 相同，所有 goroutine 都被视为主线程，f 直接在当前 goroutine 中执行。
+func (a *headless) DispatchSync(f func()) error {
+	select {
+	case <-a.done:
+		return webview.ErrClosed()
+	default:
+		f()
+		return nil
+	}
+}
+
+func (a *headless) Run() { <-a.done }
+
+func (a *headless) Close() {
+	a.once.Do(func() {
+		a.binder.Close()
+		close(a.done)
+	})
+}
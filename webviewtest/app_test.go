@@ -0,0 +1,43 @@
+// SPDX-License-Identifier: MIT
+
+package webviewtest
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/issue9/assert/v3"
+
+	"github.com/issue9/webview"
+)
+
+func TestNewApp(t *testing.T) {
+	a := assert.New(t, false)
+
+	app := NewApp()
+	a.NotNil(app)
+	a.NotError(app.Bind("add", func(x, y int) int { return x + y }))
+
+	buf := &bytes.Buffer{}
+	a.NotError(app.TypeScript(buf)).
+		Contains(buf.String(), "declare const add: RPCFunc<(arg0: number, arg1: number) => RPCPromise<number>>;")
+
+	v := 0
+	app.Dispatch(func() { v = 1 })
+	a.Equal(v, 1)
+	a.NotError(app.DispatchSync(func() { v = 2 })).Equal(v, 2)
+
+	// 在主线程中调用
+	app.Dispatch(func() {
+		a.NotError(app.DispatchSync(func() { v = 3 })).Equal(v, 3)
+	})
+	a.NotError(app.DispatchSync(func() {
+		a.NotError(app.DispatchSync(func() { v = 4 })).Equal(v, 4)
+	}))
+
+	go app.Close()
+	app.Run()
+	app.Close() // 多次关闭
+
+	a.Equal(app.DispatchSync(func() { v = 5 }), webview.ErrClosed()).Equal(v, 4)
+}
@@ -37,6 +37,9 @@ func app(w webview.App) {
 	w.Bind("add", func(a, b int) int {
 		return a + b
 	})
+	w.Bind("emit", func(event string) error {
+		return w.Emit(event, "hello")
+	})
 	w.Bind("quit", func() {
 		w.Close()
 	})
@@ -50,7 +53,11 @@ func app(w webview.App) {
 						console.log('noop res', res);
 						add(1, 2).then(function(res) {
 							console.log('add res', res);
-							quit();
+							window.webview.once('hello', function(res) {
+								console.log('emit res', res);
+								quit();
+							});
+							emit('hello');
 						});
 					});
 				};